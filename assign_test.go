@@ -0,0 +1,164 @@
+package ergolas_test
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleContext_Assign() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO())
+
+	_, err := evaluateIn(ctx, `
+		counter := fn {
+			n := 0
+			fn { n = n + 1; n }
+		}
+
+		next := call counter
+		call next
+		call next
+		println (call next)
+
+		const limit := 10
+		if true { limit := 20; println limit }
+		println limit
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// 3
+	// 20
+	// 10
+}
+
+func TestAssignment(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `
+		x := 1
+		x = x + 1
+		if true { x = x * 10 }
+		if true { x := 0 }
+
+		n := 0
+		inc := fn { n = n + 1 }
+		call inc
+		call inc
+
+		x + n
+	`)
+	if err != nil || result != int64(22) {
+		t.Fatalf("expected 22, got %v (%v)", result, err)
+	}
+}
+
+func TestAssignUndeclared(t *testing.T) {
+	_, err := evaluateIn(ergolas.NewRootContext(), `y = 1`)
+	if err == nil || err.Error() != `cannot assign to undeclared variable "y"` {
+		t.Errorf("expected undeclared variable error, got %v", err)
+	}
+
+	// the variables of a function are gone once it returns
+	_, err = evaluateIn(ergolas.NewRootContext(), `f := fn { z := 1 }; call f; z = 2`)
+	if err == nil || err.Error() != `cannot assign to undeclared variable "z"` {
+		t.Errorf("expected undeclared variable error, got %v", err)
+	}
+
+	_, err = evaluateIn(ergolas.NewRootContext(), `1 = 2`)
+	if err == nil || !strings.Contains(err.Error(), `expected identifier`) {
+		t.Errorf("expected invalid target error, got %v", err)
+	}
+}
+
+func TestConst(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `const c :: Int := 1; c`)
+	if err != nil || result != int64(1) {
+		t.Fatalf("expected 1, got %v (%v)", result, err)
+	}
+
+	for _, source := range []string{
+		`const c := 1; c = 2`,
+		`const c := 1; c := 2`,
+		`const c := 1; f := fn { c = 2 }; call f`,
+	} {
+		_, err := evaluateIn(ergolas.NewRootContext(), source)
+		if err == nil || err.Error() != `cannot assign to constant "c"` {
+			t.Errorf("%s: expected constant error, got %v", source, err)
+		}
+	}
+}
+
+func TestAssignFrozen(t *testing.T) {
+	root := newSharedRoot()
+	fork := root.Fork()
+
+	if _, err := evaluateIn(fork, `suffix = "?"`); err == nil || err.Error() != `cannot assign "suffix" in a frozen context` {
+		t.Errorf("expected frozen context error, got %v", err)
+	}
+
+	// a declaration in the fork can then be reassigned
+	if v, err := evaluateIn(fork, `suffix := "?"; suffix = "!!"; suffix`); err != nil || v != "!!" {
+		t.Errorf("expected !!, got %v (%v)", v, err)
+	}
+	if v, _ := root.GetKey("suffix"); v != "!" {
+		t.Errorf("expected root binding to be unchanged, got %v", v)
+	}
+}
+
+func TestResolveAssignment(t *testing.T) {
+	diagnostics := resolveSource(t, `
+		n := 0
+		inc := fn { n = n + 1 }
+		const limit := 10
+		println (inc) limit
+	`)
+
+	if len(diagnostics) > 0 {
+		t.Fatalf("expected no diagnostics, got %v", diagnostics)
+	}
+
+	source := `
+		missing = 1
+		const c := 1
+		c = 2
+		c := 3
+		println = 4
+	`
+	messages := []string{}
+	for _, d := range resolveSource(t, source) {
+		messages = append(messages, d.Format(source))
+	}
+
+	expected := []string{
+		`[2:3] error: cannot assign to undeclared variable "missing"`,
+		`[4:3] error: cannot assign to constant "c"`,
+		`[5:3] error: cannot assign to constant "c"`,
+		`[6:3] warning: assignment to global "println" provided by the host`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+func TestCheckAssignment(t *testing.T) {
+	source := `
+		x :: Int := 1
+		x = "one"
+		x = 2
+		y := 1
+		y = "one"
+		len y
+	`
+
+	messages := checkSource(t, source)
+
+	expected := []string{
+		`[3:7] error: cannot assign value of type String to "x" of type Int`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
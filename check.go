@@ -0,0 +1,427 @@
+package ergolas
+
+import (
+	"fmt"
+	"sort"
+)
+
+type typedVariable struct {
+	typ Type
+
+	// annotated tells whether the type of this variable was given explicitly,
+	// in that case later assignments must be compatible with it
+	annotated bool
+}
+
+type typeScope struct {
+	parent    *typeScope
+	variables map[string]*typedVariable
+}
+
+func (s *typeScope) lookup(name string) (*typedVariable, bool) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if v, ok := cur.variables[name]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+type checker struct {
+	diagnostics []Diagnostic
+}
+
+func (c *checker) report(span Span, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{SeverityError, span, fmt.Sprintf(format, args...)})
+}
+
+// Check runs the gradual type checker on a program. Unannotated values are
+// of type Any and are compatible with everything, so only the annotated parts
+// of the program and the types inferred from literals get checked.
+//
+// The globals are the types of the values the host defines in the root
+// context, usually just "ctx.Types()".
+func Check(node Node, globals map[string]Type) []Diagnostic {
+	c := &checker{}
+
+	root := &typeScope{variables: map[string]*typedVariable{}}
+	for name, typ := range globals {
+		root.variables[name] = &typedVariable{typ: typ}
+	}
+
+	c.check(node, &typeScope{root, map[string]*typedVariable{}})
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return c.diagnostics[i].Span.Start < c.diagnostics[j].Span.Start
+	})
+
+	return c.diagnostics
+}
+
+func (c *checker) typeExpression(node Node) Type {
+	typ, err := parseTypeExpression(node)
+	if err != nil {
+		c.report(node.Span(), "%v", err)
+		return AnyType
+	}
+
+	return typ
+}
+
+func (c *checker) checkStatements(nodes []Node, s *typeScope) Type {
+	var last Type = NilType
+	for _, n := range nodes {
+		last = c.check(n, s)
+	}
+
+	return last
+}
+
+func (c *checker) checkFunction(def *functionDef, s *typeScope) Type {
+	inner := &typeScope{s, map[string]*typedVariable{}}
+
+	fnType := FunctionType{Params: []Type{}, Result: AnyType}
+	for _, p := range def.Params {
+		var typ Type = AnyType
+		if p.Type != nil {
+			typ = c.typeExpression(p.Type)
+		}
+
+		fnType.Params = append(fnType.Params, typ)
+		inner.variables[p.Name] = &typedVariable{typ, p.Type != nil}
+	}
+
+	bodyType := c.checkStatements(def.Body.Children(), inner)
+
+	if def.Result == nil {
+		fnType.Result = bodyType
+		return fnType
+	}
+
+	fnType.Result = c.typeExpression(def.Result)
+	if !isAssignable(bodyType, fnType.Result) {
+		span := def.Body.Span()
+		if statements := def.Body.Children(); len(statements) > 0 {
+			span = statements[len(statements)-1].Span()
+		}
+
+		c.report(span, `cannot return value of type %v from function returning %v`, bodyType, fnType.Result)
+	}
+
+	return fnType
+}
+
+func (c *checker) check(node Node, s *typeScope) Type {
+	switch node.Type() {
+	case ProgramNode, ExpressionsNode:
+		return c.checkStatements(node.Children(), s)
+
+	case IntegerNode:
+		return IntType
+	case FloatNode:
+		return FloatType
+	case DurationNode:
+		return DurationType
+	case StringNode:
+		return StringType
+	case RegexNode:
+		return RegexType
+	case QuotedExpressionNode:
+		if node.Children()[0].Type() == IdentifierNode {
+			return SymbolType
+		}
+
+		return QuotedType
+
+	case IdentifierNode:
+		if v, ok := s.lookup(node.Metadata()["Value"].(string)); ok {
+			return v.typ
+		}
+
+		return AnyType
+
+	case ParenthesisNode:
+		return c.check(node.Children()[0], s)
+
+	case BlockNode:
+		return c.checkFunction(&functionDef{Body: node}, s)
+
+	case ListNode:
+		for _, n := range node.Children() {
+			c.check(n, s)
+		}
+
+		return ListType
+
+	case FunctionCallNode:
+		if def, ok := functionLiteral(node); ok {
+			return c.checkFunction(def, s)
+		}
+		if subject, arms, ok := matchExpression(node); ok {
+			return c.checkMatch(subject, arms, s)
+		}
+
+		return c.checkCall(node, node.Children()[0], node.Children()[1:], s)
+
+	case BinaryExpressionNode:
+		return c.checkBinary(node, s)
+	}
+
+	for _, n := range node.Children() {
+		c.check(n, s)
+	}
+
+	return AnyType
+}
+
+// checkCall checks the call of a function with the given arguments, node is
+// the whole call expression
+func (c *checker) checkCall(node Node, callee Node, args []Node, s *typeScope) Type {
+	calleeType := c.check(callee, s)
+
+	argTypes := []Type{}
+	for _, arg := range args {
+		argTypes = append(argTypes, c.check(arg, s))
+	}
+
+	switch calleeType := calleeType.(type) {
+	case FunctionType:
+		if len(argTypes) != len(calleeType.Params) {
+			c.report(node.Span(), `expected %d arguments, got %d`, len(calleeType.Params), len(argTypes))
+			return calleeType.Result
+		}
+
+		for i, argType := range argTypes {
+			if !isAssignable(argType, calleeType.Params[i]) {
+				c.report(args[i].Span(), `cannot use value of type %v as %v in argument %d`, argType, calleeType.Params[i], i+1)
+			}
+		}
+
+		return calleeType.Result
+	case BasicType:
+		if calleeType != AnyType && calleeType != FnType {
+			c.report(callee.Span(), `cannot call value of type %v`, calleeType)
+		}
+	}
+
+	return AnyType
+}
+
+// checkMatch checks all the arms of a match, the result is of the type of
+// the arms if they all agree
+func (c *checker) checkMatch(subject Node, arms []matchArm, s *typeScope) Type {
+	c.check(subject, s)
+
+	var result Type
+	for i, arm := range arms {
+		inner := &typeScope{s, map[string]*typedVariable{}}
+		c.declarePattern(arm.Pattern, inner)
+
+		if arm.Guard != nil {
+			c.check(arm.Guard, inner)
+		}
+
+		var typ Type
+		if arm.Body.Type() == BlockNode {
+			typ = c.checkStatements(arm.Body.Children(), inner)
+		} else {
+			typ = c.check(arm.Body, inner)
+		}
+
+		if i == 0 {
+			result = typ
+		} else if typ != result {
+			result = AnyType
+		}
+	}
+
+	if result == nil {
+		return AnyType
+	}
+
+	return result
+}
+
+// declarePattern declares the variables bound by a pattern, their type is
+// known only when annotated like in "(n :: Int)"
+func (c *checker) declarePattern(pattern Node, s *typeScope) {
+	switch pattern.Type() {
+	case IdentifierNode:
+		for _, name := range patternBindings(pattern) {
+			s.variables[name.Metadata()["Value"].(string)] = &typedVariable{AnyType, false}
+		}
+
+	case ParenthesisNode:
+		name, typeAst, ok := typeAnnotation(pattern.Children()[0])
+		if !ok {
+			c.declarePattern(pattern.Children()[0], s)
+			return
+		}
+
+		typ := c.typeExpression(typeAst)
+		for _, name := range patternBindings(name) {
+			s.variables[name.Metadata()["Value"].(string)] = &typedVariable{typ, false}
+		}
+
+	case ListNode:
+		for _, item := range pattern.Children() {
+			c.declarePattern(item, s)
+		}
+
+	case BlockNode:
+		for _, name := range patternBindings(pattern) {
+			s.variables[name.Metadata()["Value"].(string)] = &typedVariable{AnyType, false}
+		}
+	}
+}
+
+func (c *checker) checkBinary(node Node, s *typeScope) Type {
+	lhs := node.Children()[0]
+	op := node.Children()[1].Metadata()["Value"].(string)
+	rhs := node.Children()[2]
+
+	if def, ok := functionLiteral(node); ok {
+		return c.checkFunction(def, s)
+	}
+
+	switch op {
+	case ":=":
+		rhsType := c.check(rhs, s)
+
+		target, typeAst, annotated := typeAnnotation(lhs)
+		if !annotated {
+			target = lhs
+		}
+		target, _ = constDeclaration(target)
+		if isDestructuring(target) {
+			c.declarePattern(target, s)
+			return NilType
+		}
+		if target.Type() != IdentifierNode {
+			return NilType
+		}
+
+		name := target.Metadata()["Value"].(string)
+
+		if annotated {
+			typ := c.typeExpression(typeAst)
+			if !isAssignable(rhsType, typ) {
+				c.report(rhs.Span(), `cannot assign value of type %v to "%s" of type %v`, rhsType, name, typ)
+			}
+
+			s.variables[name] = &typedVariable{typ, true}
+			return NilType
+		}
+
+		if v, ok := s.variables[name]; ok && v.annotated {
+			if !isAssignable(rhsType, v.typ) {
+				c.report(rhs.Span(), `cannot assign value of type %v to "%s" of type %v`, rhsType, name, v.typ)
+			}
+
+			return NilType
+		}
+
+		s.variables[name] = &typedVariable{rhsType, false}
+		return NilType
+
+	case "=":
+		rhsType := c.check(rhs, s)
+		if lhs.Type() != IdentifierNode {
+			return NilType
+		}
+
+		name := lhs.Metadata()["Value"].(string)
+		v, ok := s.lookup(name)
+		if !ok {
+			return NilType
+		}
+
+		if v.annotated {
+			if !isAssignable(rhsType, v.typ) {
+				c.report(rhs.Span(), `cannot assign value of type %v to "%s" of type %v`, rhsType, name, v.typ)
+			}
+		} else if v.typ != rhsType {
+			// the assignment can happen in a function called later
+			v.typ = AnyType
+		}
+
+		return NilType
+
+	case "::":
+		lhsType := c.check(lhs, s)
+		typ := c.typeExpression(rhs)
+		if !isAssignable(lhsType, typ) {
+			c.report(lhs.Span(), `value of type %v is not of type %v`, lhsType, typ)
+		}
+
+		return typ
+
+	case "|>":
+		callee, args, at := pipeCall(rhs)
+		args = append(args[:at:at], append([]Node{lhs}, args[at:]...)...)
+
+		return c.checkCall(node, callee, args, s)
+
+	case "<-":
+		c.check(lhs, s)
+		c.check(rhs, s)
+
+		return NilType
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		c.check(lhs, s)
+		c.check(rhs, s)
+
+		return BoolType
+
+	case "&&", "||":
+		lhsType, rhsType := c.check(lhs, s), c.check(rhs, s)
+		if lhsType == rhsType {
+			return lhsType
+		}
+
+		return AnyType
+
+	case "^":
+		lhsType, rhsType := c.check(lhs, s), c.check(rhs, s)
+		if lhsType == AnyType || rhsType == AnyType {
+			return AnyType
+		}
+
+		if (lhsType == IntType || lhsType == FloatType) && (rhsType == IntType || rhsType == FloatType) {
+			if lhsType == IntType && rhsType == IntType {
+				// negative exponents give a float
+				return AnyType
+			}
+
+			return FloatType
+		}
+
+		c.report(node.Span(), `cannot apply operator "%s" to types %v and %v`, op, lhsType, rhsType)
+		return AnyType
+
+	case "+", "-", "*", "/", "%":
+		lhsType, rhsType := c.check(lhs, s), c.check(rhs, s)
+		if lhsType == AnyType || rhsType == AnyType {
+			return AnyType
+		}
+
+		if lhsType == rhsType {
+			if lhsType == IntType || lhsType == FloatType || (op == "+" && lhsType == StringType) {
+				return lhsType
+			}
+		}
+		if typ, ok := timeOperationType(op, lhsType, rhsType); ok {
+			return typ
+		}
+
+		c.report(node.Span(), `cannot apply operator "%s" to types %v and %v`, op, lhsType, rhsType)
+		return AnyType
+	}
+
+	c.check(lhs, s)
+	c.check(rhs, s)
+
+	return AnyType
+}
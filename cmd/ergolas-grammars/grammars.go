@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aziis98/ergolas"
+)
+
+// tokenStyle tells how each token type gets highlighted, every token type of
+// the tokenizer must be listed here or the generator fails
+type tokenStyle struct {
+	// scope is the TextMate scope, empty for tokens that are not highlighted
+	scope string
+	// vimGroup is the Vim highlight group, empty for tokens that are not highlighted
+	vimGroup string
+	// treeSitterRule is the name of the tree-sitter rule for this token
+	treeSitterRule string
+}
+
+var tokenStyles = map[ergolas.TokenType]tokenStyle{
+	ergolas.FloatToken:       {"constant.numeric.float.ergolas", "Float", "float"},
+	ergolas.IntegerToken:     {"constant.numeric.integer.ergolas", "Number", "integer"},
+	ergolas.DurationToken:    {"constant.numeric.duration.ergolas", "Number", "duration"},
+	ergolas.StringToken:      {"string.quoted.double.ergolas", "String", "string"},
+	ergolas.RegexToken:       {"string.regexp.ergolas", "String", "regex"},
+	ergolas.QuoteToken:       {"keyword.operator.quote.ergolas", "Special", "quote"},
+	ergolas.UnquoteToken:     {"keyword.operator.unquote.ergolas", "Special", "unquote"},
+	ergolas.LOperatorToken:   {"keyword.operator.ergolas", "Operator", "l_operator"},
+	ergolas.ROperatorToken:   {"keyword.operator.assignment.ergolas", "Statement", "r_operator"},
+	ergolas.PunctuationToken: {"punctuation.ergolas", "Delimiter", "punctuation"},
+	ergolas.IdentifierToken:  {"variable.other.ergolas", "Identifier", "identifier"},
+	ergolas.CommentToken:     {"comment.line.number-sign.ergolas", "Comment", "comment"},
+	ergolas.WhitespaceToken:  {"", "", "whitespace"},
+	ergolas.NewlineToken:     {"", "", "newline"},
+}
+
+// nodeRules are the tree-sitter rules for each node type of the parser, every
+// node type must be listed here or the generator fails. Node types that are
+// directly a token or that are not produced from the source have no rule.
+var nodeRules = map[ergolas.NodeType]string{
+	ergolas.ProgramNode:           `program: $ => seq(repeat($.newline), repeat(seq($._expression, optional(';'), repeat($.newline))))`,
+	ergolas.ExpressionsNode:       ``,
+	ergolas.FunctionCallNode:      `function_call: $ => prec.left(1, seq($._property_or_value, repeat1(seq($._argument, optional(',')))))`,
+	ergolas.BinaryExpressionNode:  `binary: $ => choice(prec.right(0, seq($._intermediate, $.r_operator, $._expression)), prec.left(2, seq($._argument, $.l_operator, $._property_or_value)))`,
+	ergolas.UnaryExpressionNode:   `unary: $ => seq($.r_operator, $._property_or_value)`,
+	ergolas.QuotedExpressionNode:  `quoted: $ => seq($.quote, $._property_or_value)`,
+	ergolas.UnquoteExpressionNode: `unquote_expression: $ => seq($.unquote, $._property_or_value)`,
+	ergolas.PropertyAccessNode:    `property_access: $ => prec.left(3, seq($._property_or_value, '.', $.identifier))`,
+	ergolas.ParenthesisNode:       `parenthesis: $ => seq('(', $._expression, ')')`,
+	ergolas.IdentifierNode:        ``,
+	ergolas.BlockNode:             `block: $ => seq('{', repeat($.newline), repeat(seq($._expression, optional(';'), repeat($.newline))), '}')`,
+	ergolas.ListNode:              `list: $ => seq('[', repeat($.newline), repeat(seq($._argument, optional(','), repeat($.newline))), ']')`,
+	ergolas.IntegerNode:           ``,
+	ergolas.FloatNode:             ``,
+	ergolas.DurationNode:          ``,
+	ergolas.StringNode:            ``,
+	ergolas.RegexNode:             ``,
+	ergolas.OperatorNode:          ``,
+}
+
+// treeSitterHelperRules glue together the node rules following the grammar
+// in the doc comments of the parser
+var treeSitterHelperRules = []string{
+	`_expression: $ => choice($.binary, $._intermediate)`,
+	`_intermediate: $ => choice($.function_call, $._argument)`,
+	`_argument: $ => choice(prec.left(2, seq($._argument, $.l_operator, $._property_or_value)), $._property_or_value)`,
+	`_property_or_value: $ => choice($.property_access, $.parenthesis, $.block, $.list, $.identifier, $.integer, $.float, $.duration, $.string, $.regex, $.quoted, $.unquote_expression, $.unary)`,
+}
+
+// generate returns the contents of all grammar files by their path
+func generate() (map[string]string, error) {
+	rules := ergolas.TokenRules()
+	for _, r := range rules {
+		if _, ok := tokenStyles[r.Type]; !ok {
+			return nil, fmt.Errorf(`no highlighting style for token type %q`, r.Type)
+		}
+	}
+	for _, typ := range ergolas.NodeTypes {
+		if _, ok := nodeRules[typ]; !ok {
+			return nil, fmt.Errorf(`no tree-sitter rule for node type %q`, typ)
+		}
+	}
+
+	textMate, err := generateTextMate(rules)
+	if err != nil {
+		return nil, err
+	}
+	treeSitter, err := generateTreeSitter(rules)
+	if err != nil {
+		return nil, err
+	}
+	vim, err := generateVim(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"textmate/ergolas.tmLanguage.json": textMate,
+		"tree-sitter/grammar.js":           treeSitter,
+		"vim/syntax/ergolas.vim":           vim,
+	}, nil
+}
+
+const header = "Code generated by cmd/ergolas-grammars from the tokenizer rules; DO NOT EDIT."
+
+func generateTextMate(rules []ergolas.TokenRule) (string, error) {
+	type pattern struct {
+		Name  string `json:"name"`
+		Match string `json:"match"`
+	}
+
+	patterns := []pattern{}
+	for _, r := range rules {
+		style := tokenStyles[r.Type]
+		if style.scope == "" {
+			continue
+		}
+
+		match, err := translateRule(r, flavorOniguruma)
+		if err != nil {
+			return "", err
+		}
+
+		patterns = append(patterns, pattern{style.scope, match})
+	}
+
+	sb := &strings.Builder{}
+	enc := json.NewEncoder(sb)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(map[string]any{
+		"comment":   header,
+		"name":      "Ergolas",
+		"scopeName": "source.ergolas",
+		"fileTypes": []string{"erg"},
+		"patterns":  patterns,
+	}); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func generateTreeSitter(rules []ergolas.TokenRule) (string, error) {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "// %s\n\n", header)
+	fmt.Fprintf(sb, "module.exports = grammar({\n")
+	fmt.Fprintf(sb, "  name: 'ergolas',\n\n")
+
+	fmt.Fprintf(sb, "  extras: $ => [")
+	first := true
+	for _, r := range rules {
+		if r.Ignore {
+			if !first {
+				fmt.Fprintf(sb, ", ")
+			}
+			fmt.Fprintf(sb, "$.%s", tokenStyles[r.Type].treeSitterRule)
+			first = false
+		}
+	}
+	fmt.Fprintf(sb, "],\n\n")
+
+	fmt.Fprintf(sb, "  word: $ => $.identifier,\n\n")
+	fmt.Fprintf(sb, "  rules: {\n")
+
+	for _, typ := range ergolas.NodeTypes {
+		if rule := nodeRules[typ]; rule != "" {
+			fmt.Fprintf(sb, "    %s,\n", rule)
+		}
+	}
+	for _, rule := range treeSitterHelperRules {
+		fmt.Fprintf(sb, "    %s,\n", rule)
+	}
+
+	// tree-sitter has no ordered choice between tokens, so precedences are
+	// used to reproduce the rule order of the tokenizer. Token types with more
+	// rules get a single tree-sitter rule with a choice between them.
+	types := []ergolas.TokenType{}
+	alternatives := map[ergolas.TokenType][]string{}
+	for i, r := range rules {
+		re, err := translateRule(r, flavorJavaScript)
+		if err != nil {
+			return "", err
+		}
+
+		if _, ok := alternatives[r.Type]; !ok {
+			types = append(types, r.Type)
+		}
+		alternatives[r.Type] = append(alternatives[r.Type], fmt.Sprintf("prec(%d, /%s/)", len(rules)-i, re))
+	}
+
+	fmt.Fprintf(sb, "\n")
+	for _, typ := range types {
+		token := alternatives[typ][0]
+		if len(alternatives[typ]) > 1 {
+			token = fmt.Sprintf("choice(%s)", strings.Join(alternatives[typ], ", "))
+		}
+
+		fmt.Fprintf(sb, "    %s: $ => token(%s),\n", tokenStyles[typ].treeSitterRule, token)
+	}
+
+	fmt.Fprintf(sb, "  },\n")
+	fmt.Fprintf(sb, "});\n")
+
+	return sb.String(), nil
+}
+
+func generateVim(rules []ergolas.TokenRule) (string, error) {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "\" %s\n\n", header)
+	fmt.Fprintf(sb, "if exists(\"b:current_syntax\")\n  finish\nendif\n\n")
+
+	// when more items match at the same position Vim picks the last defined
+	// one, so the rules are emitted in reverse order
+	groups := []string{}
+	for i := len(rules) - 1; i >= 0; i-- {
+		r := rules[i]
+		style := tokenStyles[r.Type]
+		if style.vimGroup == "" {
+			continue
+		}
+
+		re, err := translateRule(r, flavorVim)
+		if err != nil {
+			return "", err
+		}
+
+		group := "ergolas" + string(r.Type)
+		fmt.Fprintf(sb, "syntax match %s /%s/\n", group, re)
+		groups = append(groups, group, style.vimGroup)
+	}
+
+	fmt.Fprintf(sb, "\n")
+	for i := len(groups) - 2; i >= 0; i -= 2 {
+		fmt.Fprintf(sb, "highlight default link %s %s\n", groups[i], groups[i+1])
+	}
+
+	fmt.Fprintf(sb, "\nlet b:current_syntax = \"ergolas\"\n")
+
+	return sb.String(), nil
+}
@@ -0,0 +1,37 @@
+// Command ergolas-grammars generates syntax highlighting grammars for TextMate
+// based editors, tree-sitter and Vim from the rules of the tokenizer and the
+// node types of the parser.
+//
+// Run "go generate" in the root of the repository after changing the tokenizer.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	log.SetFlags(log.Lshortfile | log.Lmsgprefix)
+}
+
+func main() {
+	out := flag.String("out", "editors", "output directory")
+	flag.Parse()
+
+	files, err := generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for path, content := range files {
+		path = filepath.Join(*out, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+// TestGeneratedUpToDate fails when the tokenizer changed without running "go generate"
+func TestGeneratedUpToDate(t *testing.T) {
+	files, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range files {
+		actual, err := os.ReadFile(filepath.Join("..", "..", "editors", path))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(actual) != expected {
+			t.Errorf(`%s is out of date, run "go generate" in the root of the repository`, path)
+		}
+	}
+}
+
+// TestTranslatedRegexMatchesTokenizer checks the translated patterns match the
+// same text as the original ones, the JavaScript flavor is close enough to the
+// Go syntax to be compiled back
+func TestTranslatedRegexMatchesTokenizer(t *testing.T) {
+	samples := []string{
+		`3.14`, `42`, `"a \"quoted\" string"`, `:=`, `::`, `:symbol`, `$x`,
+		`=`, `==`, `|>`, `<-`, `+`, `&&`, `<=`, `(`, `}`, `an-Example_identifier`, "\n  \n\t", `# comment`, " \t",
+	}
+
+	for _, rule := range ergolas.TokenRules() {
+		translated, err := translateRegex(rule.Pattern, flavorJavaScript)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		original := regexp.MustCompile(rule.Pattern)
+		roundTrip := regexp.MustCompile(`^(?:` + translated + `)`)
+
+		for _, sample := range samples {
+			if a, b := original.FindString(sample), roundTrip.FindString(sample); a != b {
+				t.Errorf("%s: %q matches %q but translated %q matches %q", rule.Type, rule.Pattern, a, translated, b)
+			}
+		}
+	}
+}
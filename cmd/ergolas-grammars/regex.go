@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+
+	"github.com/aziis98/ergolas"
+)
+
+// flavor is a target regular expression syntax for an editor grammar
+type flavor int
+
+const (
+	// flavorOniguruma is used by TextMate grammars
+	flavorOniguruma flavor = iota
+	// flavorJavaScript is used by tree-sitter grammars
+	flavorJavaScript
+	// flavorVim is the "very magic" syntax of Vim
+	flavorVim
+)
+
+// translateRegex converts a Go regular expression to the given flavor, the
+// leading "^" anchor used by the tokenizer rules is dropped as editors always
+// match at the current position.
+func translateRegex(pattern string, f flavor) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+
+	sb := &strings.Builder{}
+	if f == flavorVim {
+		sb.WriteString(`\v`)
+	}
+
+	if err := writeRegex(sb, re, f); err != nil {
+		return "", fmt.Errorf(`cannot translate %q: %w`, pattern, err)
+	}
+
+	return sb.String(), nil
+}
+
+// translateRule converts the pattern of a tokenizer rule to the given flavor,
+// a NotFollowedBy pattern becomes a negative lookahead. The JavaScript regexes
+// of tree-sitter have no lookahead so it is dropped there.
+func translateRule(r ergolas.TokenRule, f flavor) (string, error) {
+	pattern, err := translateRegex(r.Pattern, f)
+	if err != nil || r.NotFollowedBy == "" || f == flavorJavaScript {
+		return pattern, err
+	}
+
+	next, err := translateRegex(r.NotFollowedBy, f)
+	if err != nil {
+		return "", err
+	}
+
+	if f == flavorVim {
+		return pattern + `%(` + strings.TrimPrefix(next, `\v`) + `)@!`, nil
+	}
+
+	return pattern + `(?!` + next + `)`, nil
+}
+
+func writeGroup(sb *strings.Builder, re *syntax.Regexp, f flavor) error {
+	if f == flavorVim {
+		sb.WriteString(`%(`)
+	} else {
+		sb.WriteString(`(?:`)
+	}
+	if err := writeRegex(sb, re, f); err != nil {
+		return err
+	}
+	sb.WriteString(`)`)
+	return nil
+}
+
+// writeOperand writes a sub-expression that is going to be followed by a
+// repetition operator, grouping it when needed
+func writeOperand(sb *strings.Builder, re *syntax.Regexp, f flavor) error {
+	switch re.Op {
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL, syntax.OpCapture:
+		return writeRegex(sb, re, f)
+	case syntax.OpLiteral:
+		if len(re.Rune) == 1 {
+			return writeRegex(sb, re, f)
+		}
+	}
+
+	return writeGroup(sb, re, f)
+}
+
+func writeRepeat(sb *strings.Builder, re *syntax.Regexp, f flavor) error {
+	if err := writeOperand(sb, re.Sub[0], f); err != nil {
+		return err
+	}
+
+	nonGreedy := re.Flags&syntax.NonGreedy != 0
+
+	if f == flavorVim {
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+
+		lazy := ""
+		if nonGreedy {
+			lazy = "-"
+		}
+
+		switch {
+		case !nonGreedy && min == 0 && max == -1:
+			sb.WriteString(`*`)
+		case !nonGreedy && min == 1 && max == -1:
+			sb.WriteString(`+`)
+		case !nonGreedy && min == 0 && max == 1:
+			sb.WriteString(`?`)
+		case max == -1:
+			fmt.Fprintf(sb, `{%s%d,}`, lazy, min)
+		default:
+			fmt.Fprintf(sb, `{%s%d,%d}`, lazy, min, max)
+		}
+
+		return nil
+	}
+
+	switch re.Op {
+	case syntax.OpStar:
+		sb.WriteString(`*`)
+	case syntax.OpPlus:
+		sb.WriteString(`+`)
+	case syntax.OpQuest:
+		sb.WriteString(`?`)
+	case syntax.OpRepeat:
+		if re.Max == -1 {
+			fmt.Fprintf(sb, `{%d,}`, re.Min)
+		} else {
+			fmt.Fprintf(sb, `{%d,%d}`, re.Min, re.Max)
+		}
+	}
+
+	if nonGreedy {
+		sb.WriteString(`?`)
+	}
+
+	return nil
+}
+
+func writeRegex(sb *strings.Builder, re *syntax.Regexp, f flavor) error {
+	switch re.Op {
+	case syntax.OpBeginText, syntax.OpBeginLine, syntax.OpEmptyMatch:
+		// nothing to match
+	case syntax.OpEndText, syntax.OpEndLine:
+		sb.WriteString(`$`)
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			writeRune(sb, r, f, false)
+		}
+	case syntax.OpAnyCharNotNL:
+		sb.WriteString(`.`)
+	case syntax.OpAnyChar:
+		if f == flavorVim {
+			sb.WriteString(`\_.`)
+		} else {
+			sb.WriteString(`[\s\S]`)
+		}
+	case syntax.OpCharClass:
+		writeCharClass(sb, re.Rune, f)
+	case syntax.OpCapture:
+		return writeGroup(sb, re.Sub[0], f)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		return writeRepeat(sb, re, f)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpAlternate {
+				if err := writeGroup(sb, sub, f); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := writeRegex(sb, sub, f); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i > 0 {
+				sb.WriteString(`|`)
+			}
+			if err := writeRegex(sb, sub, f); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf(`unsupported operator %v`, re.Op)
+	}
+
+	return nil
+}
+
+// writeCharClass writes a character class given as pairs of inclusive ranges,
+// classes covering all characters up to the maximum rune are written negated
+func writeCharClass(sb *strings.Builder, ranges []rune, f flavor) {
+	negated := len(ranges) > 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if negated {
+		complement := []rune{}
+		for i := 1; i+1 < len(ranges); i += 2 {
+			complement = append(complement, ranges[i]+1, ranges[i+1]-1)
+		}
+		ranges = complement
+	}
+
+	sb.WriteString(`[`)
+	if negated {
+		sb.WriteString(`^`)
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		writeRune(sb, lo, f, true)
+		if hi > lo {
+			if hi > lo+1 {
+				sb.WriteString(`-`)
+			}
+			writeRune(sb, hi, f, true)
+		}
+	}
+	sb.WriteString(`]`)
+}
+
+func writeRune(sb *strings.Builder, r rune, f flavor, inClass bool) {
+	switch r {
+	case '\t':
+		sb.WriteString(`\t`)
+		return
+	case '\n':
+		sb.WriteString(`\n`)
+		return
+	case '\r':
+		sb.WriteString(`\r`)
+		return
+	}
+
+	if r < 0x20 || r == 0x7f {
+		fmt.Fprintf(sb, `\x%02x`, r)
+		return
+	}
+	if r > unicode.MaxASCII {
+		switch {
+		case f == flavorOniguruma:
+			fmt.Fprintf(sb, `\x{%x}`, r)
+		case r > 0xffff && f == flavorVim:
+			fmt.Fprintf(sb, `\U%08x`, r)
+		default:
+			fmt.Fprintf(sb, `\u%04x`, r)
+		}
+		return
+	}
+
+	if needsEscape(r, f, inClass) {
+		sb.WriteRune('\\')
+	}
+	sb.WriteRune(r)
+}
+
+func needsEscape(r rune, f flavor, inClass bool) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+		return false
+	}
+
+	if inClass {
+		switch r {
+		case '\\', ']', '^', '-', '/':
+			return true
+		case '[':
+			// Oniguruma supports nested classes and Vim would keep the backslash
+			return f != flavorVim
+		}
+
+		return false
+	}
+
+	// in Vim "very magic" mode every punctuation character is special, the
+	// other flavors are fine with escaping punctuation anyway
+	return r != ' ' || f == flavorVim
+}
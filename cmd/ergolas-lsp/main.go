@@ -0,0 +1,25 @@
+// Command ergolas-lsp is a language server for ergolas scripts speaking the
+// Language Server Protocol over stdin and stdout.
+package main
+
+import (
+	"log"
+	"os"
+)
+
+func init() {
+	log.SetFlags(log.Lshortfile | log.Lmsgprefix)
+	log.SetPrefix("ergolas-lsp: ")
+}
+
+func main() {
+	s := newServer(os.Stdin, os.Stdout)
+	if err := s.serve(); err != nil {
+		log.Fatal(err)
+	}
+
+	// as per the protocol, exiting without a shutdown request is an error
+	if !s.shuttingDown {
+		os.Exit(1)
+	}
+}
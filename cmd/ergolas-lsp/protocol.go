@@ -0,0 +1,173 @@
+package main
+
+import (
+	"unicode/utf16"
+
+	"github.com/aziis98/ergolas"
+)
+
+// The subset of the Language Server Protocol types used by this server
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentItem struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   TextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      struct {
+		TabSize      int  `json:"tabSize"`
+		InsertSpaces bool `json:"insertSpaces"`
+	} `json:"options"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+const (
+	DiagnosticSeverityError   = 1
+	DiagnosticSeverityWarning = 2
+)
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+const (
+	CompletionItemKindFunction = 3
+	CompletionItemKindVariable = 6
+)
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+const (
+	SymbolKindFunction = 12
+	SymbolKindVariable = 13
+)
+
+type DocumentSymbol struct {
+	Name           string `json:"name"`
+	Kind           int    `json:"kind"`
+	Range          Range  `json:"range"`
+	SelectionRange Range  `json:"selectionRange"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// offsetToPosition converts a byte offset in the source to a position with the
+// character counted in UTF-16 code units as required by the protocol
+func offsetToPosition(source string, offset int) Position {
+	pos := Position{}
+	for i, r := range source {
+		if i >= offset {
+			break
+		}
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+			continue
+		}
+
+		pos.Character += len(utf16.Encode([]rune{r}))
+	}
+
+	return pos
+}
+
+// positionToOffset is the inverse of offsetToPosition
+func positionToOffset(source string, pos Position) int {
+	line, char := 0, 0
+	for i, r := range source {
+		if line == pos.Line && char >= pos.Character {
+			return i
+		}
+		if r == '\n' {
+			if line == pos.Line {
+				return i
+			}
+
+			line++
+			char = 0
+			continue
+		}
+		if line == pos.Line {
+			char += len(utf16.Encode([]rune{r}))
+		}
+	}
+
+	return len(source)
+}
+
+func spanToRange(source string, span ergolas.Span) Range {
+	return Range{offsetToPosition(source, span.Start), offsetToPosition(source, span.End)}
+}
+
+func fullRange(source string) Range {
+	return Range{Position{}, offsetToPosition(source, len(source))}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aziis98/ergolas"
+)
+
+type document struct {
+	text string
+
+	// node is the parsed program or nil if the document has syntax errors
+	node ergolas.Node
+}
+
+type server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	writeMu sync.Mutex
+
+	documents map[string]*document
+
+	// globals are the bindings of the root context scripts are evaluated
+	// with, used to resolve names and for completions
+	globals *ergolas.Context
+
+	shuttingDown bool
+	exited       bool
+}
+
+func newServer(in io.Reader, out io.Writer) *server {
+	return &server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]*document{},
+		globals:   ergolas.NewRootContext(ergolas.WithModules(os.DirFS("."))),
+	}
+}
+
+// serve handles messages until the client sends "exit" or closes the stream
+func (s *server) serve() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+
+		var malformed malformedMessageError
+		if errors.As(err, &malformed) {
+			// the id of the request can't be known so the response has a
+			// null id
+			null := json.RawMessage("null")
+			if err := s.send(&message{ID: &null, Error: &responseError{codeParseError, malformed.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.dispatch(msg); err != nil {
+			return err
+		}
+		if s.exited {
+			return nil
+		}
+	}
+}
+
+func (s *server) send(msg *message) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return writeMessage(s.out, msg)
+}
+
+func (s *server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return s.send(&message{Method: method, Params: data})
+}
+
+func (s *server) dispatch(msg *message) error {
+	result, rpcErr := s.safeHandle(msg)
+
+	// notifications don't get a response
+	if msg.ID == nil {
+		if rpcErr != nil {
+			log.Printf("error handling %s: %s", msg.Method, rpcErr.Message)
+		}
+		return nil
+	}
+
+	if rpcErr != nil {
+		return s.send(&message{ID: msg.ID, Error: rpcErr})
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return s.send(&message{ID: msg.ID, Result: data})
+}
+
+// safeHandle is like handle but a panic while handling a message becomes an
+// error response instead of stopping the server
+func (s *server) safeHandle(msg *message) (result any, rpcErr *responseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, rpcErr = nil, &responseError{codeInternalError, fmt.Sprintf(`internal error: %v`, r)}
+		}
+	}()
+
+	return s.handle(msg)
+}
+
+func decodeParams[T any](msg *message) (T, *responseError) {
+	var params T
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return params, &responseError{codeInvalidParams, err.Error()}
+	}
+
+	return params, nil
+}
+
+func (s *server) handle(msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":           1, // full document sync
+				"hoverProvider":              true,
+				"definitionProvider":         true,
+				"completionProvider":         map[string]any{},
+				"documentSymbolProvider":     true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "ergolas-lsp"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shuttingDown = true
+		return nil, nil
+	case "exit":
+		s.exited = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		params, err := decodeParams[DidOpenTextDocumentParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		params, err := decodeParams[DidChangeTextDocumentParams](msg)
+		if err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		params, err := decodeParams[DidCloseTextDocumentParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.publish(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/hover":
+		params, err := decodeParams[TextDocumentPositionParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.hover(params), nil
+	case "textDocument/definition":
+		params, err := decodeParams[TextDocumentPositionParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.definition(params), nil
+	case "textDocument/completion":
+		params, err := decodeParams[TextDocumentPositionParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.completion(params), nil
+	case "textDocument/documentSymbol":
+		params, err := decodeParams[DocumentSymbolParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.documentSymbols(params), nil
+	case "textDocument/formatting":
+		params, err := decodeParams[DocumentFormattingParams](msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.formatting(params)
+	}
+
+	if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil
+	}
+
+	return nil, &responseError{codeMethodNotFound, fmt.Sprintf(`method not found: %s`, msg.Method)}
+}
+
+// update parses the new text of a document and publishes its diagnostics
+func (s *server) update(uri, text string) *responseError {
+	doc := &document{text: text}
+	s.documents[uri] = doc
+
+	diagnostics := []Diagnostic{}
+	report := func(severity int, span ergolas.Span, message string) {
+		diagnostics = append(diagnostics, Diagnostic{spanToRange(text, span), severity, "ergolas", message})
+	}
+
+	tokens, err := ergolas.Tokenize(text)
+	if err != nil {
+		var tokErr ergolas.TokenizeError
+		errors.As(err, &tokErr)
+		report(DiagnosticSeverityError, ergolas.Span{Start: tokErr.Location, End: tokErr.Location + 1}, tokErr.Message)
+		return s.publish(uri, diagnostics)
+	}
+
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		var parseErr ergolas.ParseError
+		if errors.As(err, &parseErr) {
+			report(DiagnosticSeverityError, ergolas.Span{Start: parseErr.Location, End: parseErr.Location + 1}, parseErr.Message)
+		} else {
+			report(DiagnosticSeverityError, ergolas.Span{}, err.Error())
+		}
+		return s.publish(uri, diagnostics)
+	}
+
+	doc.node = node
+
+	found := append(ergolas.Resolve(node, s.globals.Names()), ergolas.Check(node, s.globals.Types())...)
+	for _, d := range found {
+		severity := DiagnosticSeverityError
+		if d.Severity == ergolas.SeverityWarning {
+			severity = DiagnosticSeverityWarning
+		}
+
+		report(severity, d.Span, d.Message)
+	}
+
+	return s.publish(uri, diagnostics)
+}
+
+func (s *server) publish(uri string, diagnostics []Diagnostic) *responseError {
+	if err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{uri, diagnostics}); err != nil {
+		return &responseError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+// nodeAt returns the innermost node containing the given offset
+func nodeAt(node ergolas.Node, offset int) ergolas.Node {
+	if !node.Span().Contains(offset) {
+		return nil
+	}
+
+	for _, child := range node.Children() {
+		if n := nodeAt(child, offset); n != nil {
+			return n
+		}
+	}
+
+	return node
+}
+
+// lookup returns the parsed document and the node at the given position
+func (s *server) lookup(params TextDocumentPositionParams) (*document, ergolas.Node) {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok || doc.node == nil {
+		return nil, nil
+	}
+
+	return doc, nodeAt(doc.node, positionToOffset(doc.text, params.Position))
+}
+
+func (s *server) hover(params TextDocumentPositionParams) *Hover {
+	doc, node := s.lookup(params)
+	if node == nil {
+		return nil
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "**%s**", node.Type())
+	if value, ok := node.Metadata()["Value"]; ok {
+		fmt.Fprintf(sb, " `%v`", value)
+	}
+
+	if node.Type() == ergolas.IdentifierNode {
+		if _, ok := ergolas.References(doc.node, s.globals.Names())[node.Span()]; !ok {
+			if value, err := s.globals.GetKey(node.Metadata()["Value"].(string)); err == nil {
+				fmt.Fprintf(sb, "\n\nbuiltin of type `%v`", ergolas.TypeOf(value))
+			}
+		}
+	}
+
+	return &Hover{MarkupContent{"markdown", sb.String()}, spanToRange(doc.text, node.Span())}
+}
+
+func (s *server) definition(params TextDocumentPositionParams) *Location {
+	doc, node := s.lookup(params)
+	if node == nil || node.Type() != ergolas.IdentifierNode {
+		return nil
+	}
+
+	decl, ok := ergolas.References(doc.node, s.globals.Names())[node.Span()]
+	if !ok {
+		return nil
+	}
+
+	return &Location{params.TextDocument.URI, spanToRange(doc.text, decl)}
+}
+
+func (s *server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+	seen := map[string]struct{}{}
+
+	for name, typ := range s.globals.Types() {
+		kind := CompletionItemKindVariable
+		if typ == ergolas.FnType {
+			kind = CompletionItemKindFunction
+		}
+
+		seen[name] = struct{}{}
+		items = append(items, CompletionItem{name, kind, typ.String()})
+	}
+
+	if doc, ok := s.documents[params.TextDocument.URI]; ok && doc.node != nil {
+		for _, decl := range ergolas.References(doc.node, s.globals.Names()) {
+			name := doc.text[decl.Start:decl.End]
+			if _, ok := seen[name]; ok {
+				continue
+			}
+
+			seen[name] = struct{}{}
+			items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindVariable})
+		}
+	}
+
+	return items
+}
+
+// documentSymbols lists the top level bindings of a document
+func (s *server) documentSymbols(params DocumentSymbolParams) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok || doc.node == nil {
+		return symbols
+	}
+
+	for _, stmt := range doc.node.Children() {
+		if stmt.Type() != ergolas.BinaryExpressionNode || stmt.Children()[1].Metadata()["Value"] != ":=" {
+			continue
+		}
+
+		name, value := stmt.Children()[0], stmt.Children()[2]
+		if name.Type() == ergolas.FunctionCallNode && len(name.Children()) == 2 {
+			// constant binding "const name := value"
+			name = name.Children()[1]
+		}
+		if name.Type() == ergolas.BinaryExpressionNode {
+			// annotated binding "name :: Type := value"
+			name = name.Children()[0]
+		}
+		if name.Type() != ergolas.IdentifierNode {
+			continue
+		}
+
+		kind := SymbolKindVariable
+		if ergolas.IsFunctionLiteral(value) {
+			kind = SymbolKindFunction
+		}
+
+		symbols = append(symbols, DocumentSymbol{
+			Name:           name.Metadata()["Value"].(string),
+			Kind:           kind,
+			Range:          spanToRange(doc.text, stmt.Span()),
+			SelectionRange: spanToRange(doc.text, name.Span()),
+		})
+	}
+
+	return symbols
+}
+
+// maxTabSize is the largest number of spaces accepted as indent when
+// formatting
+const maxTabSize = 16
+
+func (s *server) formatting(params DocumentFormattingParams) ([]TextEdit, *responseError) {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, &responseError{codeInvalidParams, fmt.Sprintf(`unknown document %s`, params.TextDocument.URI)}
+	}
+
+	if params.Options.TabSize < 0 || params.Options.TabSize > maxTabSize {
+		return nil, &responseError{codeInvalidParams, fmt.Sprintf(`invalid tab size %d`, params.Options.TabSize)}
+	}
+
+	indent := "\t"
+	if params.Options.InsertSpaces {
+		indent = strings.Repeat(" ", params.Options.TabSize)
+	}
+
+	formatted, err := ergolas.FormatSource(doc.text, indent)
+	if err != nil {
+		return nil, &responseError{codeParseError, err.Error()}
+	}
+
+	return []TextEdit{{fullRange(doc.text), formatted}}, nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// client is an in-process LSP client talking to a server through pipes
+type client struct {
+	t      *testing.T
+	w      io.Writer
+	r      *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	c := &client{t: t, w: clientOut, r: bufio.NewReader(clientIn), done: make(chan error, 1)}
+	go func() {
+		c.done <- newServer(serverIn, serverOut).serve()
+		serverOut.Close()
+	}()
+
+	c.request("initialize", map[string]any{}, nil)
+	c.notify("initialized", map[string]any{})
+
+	return c
+}
+
+func (c *client) write(msg *message) {
+	c.t.Helper()
+	if err := writeMessage(c.w, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) read() *message {
+	c.t.Helper()
+	msg, err := readMessage(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	data, _ := json.Marshal(params)
+	c.write(&message{Method: method, Params: data})
+}
+
+// request sends a request and decodes the result of its response into result
+func (c *client) request(method string, params any, result any) {
+	c.t.Helper()
+
+	msg := c.call(method, params)
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %s", method, msg.Error.Message)
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+// call sends a request and returns its response
+func (c *client) call(method string, params any) *message {
+	c.t.Helper()
+
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	data, _ := json.Marshal(params)
+	c.write(&message{ID: &id, Method: method, Params: data})
+
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			continue // skip notifications
+		}
+
+		return msg
+	}
+}
+
+func (c *client) open(uri, text string) PublishDiagnosticsParams {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocumentItem{URI: uri, Text: text}})
+
+	msg := c.read()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %q", msg.Method)
+	}
+
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params
+}
+
+func (c *client) close() {
+	c.t.Helper()
+	c.request("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func position(uri string, line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocumentIdentifier{uri}, Position{line, char}}
+}
+
+const testURI = "file:///test.erg"
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	diags := c.open(testURI, "x := 1\nprintln y\n")
+	if len(diags.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags.Diagnostics)
+	}
+
+	d := diags.Diagnostics[0]
+	if d.Message != `undefined variable "y"` || d.Range.Start != (Position{1, 8}) {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+
+	diags = c.open("file:///syntax.erg", "x := (1 + 2")
+	if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Severity != DiagnosticSeverityError {
+		t.Fatalf("expected a syntax error, got %v", diags.Diagnostics)
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(testURI, "answer := 42\nprintln answer\n")
+
+	var hover Hover
+	c.request("textDocument/hover", position(testURI, 1, 10), &hover)
+	if hover.Contents.Value != "**Identifier** `answer`" {
+		t.Fatalf("unexpected hover %q", hover.Contents.Value)
+	}
+
+	c.request("textDocument/hover", position(testURI, 1, 2), &hover)
+	if !strings.Contains(hover.Contents.Value, "builtin of type `Fn`") {
+		t.Fatalf("unexpected hover %q", hover.Contents.Value)
+	}
+
+	var loc Location
+	c.request("textDocument/definition", position(testURI, 1, 10), &loc)
+	if loc.URI != testURI || loc.Range != (Range{Position{0, 0}, Position{0, 6}}) {
+		t.Fatalf("unexpected definition %+v", loc)
+	}
+}
+
+func TestCompletionAndSymbols(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(testURI, "add := fn a b { a + b }\nresult := add 1 2\n")
+
+	var items []CompletionItem
+	c.request("textDocument/completion", position(testURI, 1, 0), &items)
+
+	labels := map[string]bool{}
+	for _, item := range items {
+		labels[item.Label] = true
+	}
+	for _, expected := range []string{"println", "add", "result"} {
+		if !labels[expected] {
+			t.Errorf("missing completion %q in %v", expected, items)
+		}
+	}
+
+	var symbols []DocumentSymbol
+	c.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocumentIdentifier{testURI}}, &symbols)
+	if len(symbols) != 2 || symbols[0].Name != "add" || symbols[0].Kind != SymbolKindFunction || symbols[1].Kind != SymbolKindVariable {
+		t.Fatalf("unexpected symbols %+v", symbols)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	c.open(testURI, "f := fn x {\nx +   1 # comment\n}\n\n\n\nprintln (f 1)   ")
+
+	params := DocumentFormattingParams{TextDocument: TextDocumentIdentifier{testURI}}
+	params.Options.TabSize = 2
+	params.Options.InsertSpaces = true
+
+	var edits []TextEdit
+	c.request("textDocument/formatting", params, &edits)
+
+	expected := "f := fn x {\n  x + 1 # comment\n}\n\nprintln (f 1)\n"
+	if len(edits) != 1 || edits[0].NewText != expected {
+		t.Fatalf("unexpected edits %q", edits)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": `
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		t.Fatal(err)
+	}
+
+	// the response to a malformed message has a null id
+	msg := c.read()
+	if msg.Error == nil || msg.Error.Code != codeParseError {
+		t.Fatalf("expected a parse error, got %+v", msg)
+	}
+
+	// the body of an oversized message is skipped and the next one is read
+	body = strings.Repeat(" ", maxMessageSize+1)
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = c.read()
+	if msg.Error == nil || msg.Error.Code != codeParseError {
+		t.Fatalf("expected a parse error, got %+v", msg)
+	}
+
+	c.open(testURI, "x := 1")
+
+	for _, tabSize := range []int{-1, 1 << 30} {
+		params := DocumentFormattingParams{TextDocument: TextDocumentIdentifier{testURI}}
+		params.Options.TabSize = tabSize
+		params.Options.InsertSpaces = true
+
+		msg = c.call("textDocument/formatting", params)
+		if msg.Error == nil || msg.Error.Code != codeInvalidParams {
+			t.Fatalf("tab size %d: expected an invalid params error, got %+v", tabSize, msg)
+		}
+	}
+}
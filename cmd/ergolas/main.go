@@ -0,0 +1,168 @@
+// Command ergolas runs scripts from files, the command line or stdin.
+//
+//	ergolas run script.erg [args...]
+//	ergolas -e 'println "Hello, World!"' [args...]
+//	echo 'println "Hello, World!"' | ergolas
+//
+// Scripts receive the remaining arguments in the "args" list. The "fs", "env"
+// and "exec" builtins are granted only by the -allow-fs, -allow-env and
+// -allow-exec flags, files and processes are then in the current directory.
+// The process exits with the code passed to "exit", with 1 if the script
+// fails and with 2 for invalid usage.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aziis98/ergolas"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage: ergolas [flags] run <file> [args...]
+       ergolas [flags] -e <source> [args...]
+       ergolas [flags] [-] [args...]    reads the script from stdin
+
+flags:
+`
+
+// script is the source code to run and where it comes from
+type script struct {
+	name   string
+	source string
+	// dir is the directory modules get imported from
+	dir string
+}
+
+// run runs the command with the given arguments and returns its exit code
+func run(argv []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ergolas", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	expr := flags.String("e", "", "evaluate the given source code")
+	dumpTokens := flags.Bool("dump-tokens", false, "print the tokens of the script instead of running it")
+	dumpAST := flags.Bool("dump-ast", false, "print the syntax tree of the script instead of running it")
+	allowFS := flags.Bool("allow-fs", false, "grant reading and writing files in the current directory")
+	allowEnv := flags.Bool("allow-env", false, "grant reading environment variables")
+	allowExec := flags.Bool("allow-exec", false, "grant running processes, these are not confined to the current directory")
+
+	if err := flags.Parse(argv); err != nil {
+		return 2
+	}
+
+	s, args, err := loadScript(flags, *expr, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	tokens, err := ergolas.Tokenize(s.source)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", s.name, err)
+		return 1
+	}
+
+	if *dumpTokens {
+		for _, t := range tokens {
+			fmt.Fprintf(stdout, "%-12s %q\n", t.Type, t.Value)
+		}
+	}
+
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", s.name, err)
+		return 1
+	}
+
+	if *dumpAST {
+		ergolas.FprintAST(stdout, node)
+	}
+	if *dumpTokens || *dumpAST {
+		return 0
+	}
+
+	scriptArgs, err := ergolas.ToValue(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", s.name, err)
+		return 1
+	}
+
+	opts := []ergolas.ContextOption{
+		ergolas.WithModules(os.DirFS(s.dir)),
+		ergolas.WithStdout(stdout),
+		ergolas.WithStderr(stderr),
+		ergolas.WithStdin(stdin),
+		ergolas.WithBindings(map[string]any{
+			"args": scriptArgs,
+		}),
+	}
+	if *allowFS {
+		opts = append(opts, ergolas.WithFS("."))
+	}
+	if *allowEnv {
+		opts = append(opts, ergolas.WithEnv(os.LookupEnv))
+	}
+	if *allowExec {
+		opts = append(opts, ergolas.WithExec("."))
+	}
+
+	ctx := ergolas.NewRootContext(opts...)
+
+	// without limits this only recovers panics of the builtins
+	if _, err := ergolas.EvaluateWithOptions(node, ctx, ergolas.EvalOptions{}); err != nil {
+		var exitErr ergolas.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code
+		}
+
+		fmt.Fprintf(stderr, "%s: %v\n", s.name, err)
+		return 1
+	}
+
+	return 0
+}
+
+// loadScript returns the script selected by the command line and the
+// arguments to pass to it
+func loadScript(flags *flag.FlagSet, expr string, stdin io.Reader) (script, []string, error) {
+	args := flags.Args()
+
+	if expr != "" {
+		return script{"<expr>", expr, "."}, args, nil
+	}
+
+	if len(args) > 0 && args[0] == "run" {
+		if len(args) < 2 {
+			return script{}, nil, fmt.Errorf(`expected a file to run`)
+		}
+
+		source, err := os.ReadFile(args[1])
+		if err != nil {
+			return script{}, nil, err
+		}
+
+		return script{args[1], string(source), filepath.Dir(args[1])}, args[2:], nil
+	}
+
+	if len(args) > 0 && args[0] == "-" {
+		args = args[1:]
+	}
+
+	source, err := io.ReadAll(stdin)
+	if err != nil {
+		return script{}, nil, err
+	}
+
+	return script{"<stdin>", string(source), "."}, args, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(t *testing.T, stdin string, argv ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr strings.Builder
+	code := run(argv, strings.NewReader(stdin), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunFile(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "greet.erg")
+
+	writeFile(t, filepath.Join(dir, "lib", "names.erg"), `default-name := "World"`)
+	writeFile(t, script, strings.Join([]string{
+		`#!/usr/bin/env -S ergolas run`,
+		`names := import "lib/names"`,
+		`name := if ((len args) > 0) { at args 0 } names.default-name`,
+		`println "Hello, " name "!"`,
+		`exit (len args)`,
+	}, "\n"))
+
+	tests := []struct {
+		args   []string
+		code   int
+		output string
+	}{
+		{nil, 0, "Hello, World!\n"},
+		{[]string{"Alice"}, 1, "Hello, Alice!\n"},
+		{[]string{"Alice", "Bob"}, 2, "Hello, Alice!\n"},
+	}
+
+	for _, test := range tests {
+		code, stdout, stderr := runCommand(t, "", append([]string{"run", script}, test.args...)...)
+		if code != test.code || stdout != test.output || stderr != "" {
+			t.Errorf("%v: expected code %d and output %q, got %d, %q and %q", test.args, test.code, test.output, code, stdout, stderr)
+		}
+	}
+}
+
+func TestRunSources(t *testing.T) {
+	tests := []struct {
+		stdin  string
+		argv   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"", []string{"-e", `println (at args 1)`, "a", "b"}, 0, "b\n", ""},
+		{`println "from stdin"`, nil, 0, "from stdin\n", ""},
+		{`println (len args)`, []string{"-", "x"}, 0, "1\n", ""},
+		{`println (upper (readline))`, []string{"-e", `println (upper (call readline))`}, 0, "PRINTLN (UPPER (READLINE))\n", ""},
+		{"", []string{"-e", `x := missing`}, 1, "", "<expr>: unbound variable \"missing\"\n"},
+		{"", []string{"-e", `(1 +`}, 1, "", ""},
+		{"", []string{"run"}, 2, "", "error: expected a file to run\n"},
+		{"", []string{"-unknown"}, 2, "", ""},
+		{"", []string{"run", "missing.erg"}, 2, "", ""},
+	}
+
+	for _, test := range tests {
+		code, stdout, stderr := runCommand(t, test.stdin, test.argv...)
+		if code != test.code || stdout != test.stdout || (test.stderr != "" && stderr != test.stderr) {
+			t.Errorf("%v: expected code %d, stdout %q and stderr %q, got %d, %q and %q", test.argv, test.code, test.stdout, test.stderr, code, stdout, stderr)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	code, stdout, _ := runCommand(t, "", "--dump-tokens", "--dump-ast", "-e", `exit 3`)
+	if code != 0 {
+		t.Errorf("expected the script not to run, got exit code %d", code)
+	}
+
+	expected := strings.Join([]string{
+		`Identifier   "exit"`,
+		`Integer      "3"`,
+		`- Program`,
+		`  - FunctionCall`,
+		`    - Identifier { Value: "exit" }`,
+		`    - Integer { Value: "3" }`,
+		``,
+	}, "\n")
+	if stdout != expected {
+		t.Errorf("expected output\n%s\ngot\n%s", expected, stdout)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Setenv("ERGOLAS_TEST", "value")
+
+	code, _, stderr := runCommand(t, "", "-e", `env.get "ERGOLAS_TEST"`)
+	if code != 1 || !strings.Contains(stderr, `unbound variable "env"`) {
+		t.Errorf("expected env not to be granted by default, got %d and %q", code, stderr)
+	}
+
+	code, stdout, stderr := runCommand(t, "", "-allow-env", "-e", `println (env.get "ERGOLAS_TEST")`)
+	if code != 0 || stdout != "value\n" || stderr != "" {
+		t.Errorf("expected env to be granted, got %d, %q and %q", code, stdout, stderr)
+	}
+
+	for flag, name := range map[string]string{"-allow-fs": "fs", "-allow-exec": "exec"} {
+		code, _, stderr := runCommand(t, "", "-e", name)
+		if code != 1 || !strings.Contains(stderr, `unbound variable "`+name+`"`) {
+			t.Errorf("expected %s not to be granted by default, got %d and %q", name, code, stderr)
+		}
+
+		if code, _, stderr := runCommand(t, "", flag, "-e", name); code != 0 {
+			t.Errorf("expected %s to grant %s, got %d and %q", flag, name, code, stderr)
+		}
+	}
+}
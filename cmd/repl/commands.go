@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aziis98/ergolas"
+)
+
+// settings are the toggles changed by meta-commands
+var settings = struct {
+	ast, tokens, time bool
+}{
+	ast: true,
+}
+
+// command is a meta-command of the repl, these are inputs starting with ":"
+// followed by the name of the command
+type command struct {
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"ast": {
+			usage: ":ast on|off",
+			help:  "show the syntax tree of each input",
+			run:   toggle(&settings.ast),
+		},
+		"tokens": {
+			usage: ":tokens on|off",
+			help:  "show the tokens of each input",
+			run:   toggle(&settings.tokens),
+		},
+		"time": {
+			usage: ":time on|off",
+			help:  "show how long each evaluation took",
+			run:   toggle(&settings.time),
+		},
+		"env": {
+			usage: ":env [all]",
+			help:  "show the bindings defined in the repl, with \"all\" also the builtins",
+			run:   printEnv,
+		},
+		"load": {
+			usage: ":load <file>",
+			help:  "evaluate a file in the repl context",
+			run: func(args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf(`expected a file`)
+				}
+
+				source, err := os.ReadFile(args[0])
+				if err != nil {
+					return err
+				}
+
+				processInput(string(source))
+				return nil
+			},
+		},
+		"reset": {
+			usage: ":reset",
+			help:  "remove all the bindings defined in the repl",
+			run: func(args []string) error {
+				ctx = root.Fork()
+				return nil
+			},
+		},
+		"help": {
+			usage: ":help",
+			help:  "show this help",
+			run: func(args []string) error {
+				printHelp()
+				return nil
+			},
+		},
+	}
+}
+
+// parseCommand returns the command and its arguments if the input is a
+// meta-command, other inputs starting with ":" are just quoted expressions
+func parseCommand(input string) (command, []string, bool) {
+	if !strings.HasPrefix(input, ":") {
+		return command{}, nil, false
+	}
+
+	fields := strings.Fields(input[1:])
+	if len(fields) == 0 {
+		return command{}, nil, false
+	}
+
+	cmd, ok := commands[fields[0]]
+	return cmd, fields[1:], ok
+}
+
+func toggle(setting *bool) func(args []string) error {
+	return func(args []string) error {
+		if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+			return fmt.Errorf(`expected "on" or "off"`)
+		}
+
+		*setting = args[0] == "on"
+		return nil
+	}
+}
+
+func printEnv(args []string) error {
+	scope := ctx
+	if len(args) == 1 && args[0] == "all" {
+		scope = nil
+	} else if len(args) > 0 {
+		return fmt.Errorf(`expected nothing or "all"`)
+	}
+
+	for _, name := range ctx.Names() {
+		if scope != nil {
+			if _, ok := scope.Bindings[name]; !ok {
+				continue
+			}
+		}
+
+		value, _ := ctx.GetKey(name)
+		fmt.Printf("%s :: %v = %s\n", name, ergolas.TypeOf(value), formatValue(value))
+	}
+
+	return nil
+}
+
+func printHelp() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("  %-16s %s\n", commands[name].usage, commands[name].help)
+	}
+}
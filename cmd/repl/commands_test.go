@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := map[string]string{
+		`:ast off`:        "off",
+		`:load  a.erg`:    "a.erg",
+		`:reset`:          "",
+		`:quoted`:         "-",
+		`:(1 + 2)`:        "-",
+		`println "hello"`: "-",
+	}
+
+	for input, expected := range tests {
+		_, args, ok := parseCommand(input)
+		if !ok {
+			if expected != "-" {
+				t.Errorf("%q: expected a command", input)
+			}
+			continue
+		}
+
+		if actual := strings.Join(args, " "); expected == "-" || actual != expected {
+			t.Errorf("%q: expected arguments %q, got %q", input, expected, actual)
+		}
+	}
+}
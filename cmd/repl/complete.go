@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aziis98/ergolas"
+)
+
+// completer completes meta-commands, the names bound in the repl context and
+// the keys of maps after a property access like "utils.sh"
+type completer struct{}
+
+func (completer) Do(line []rune, pos int) ([][]rune, int) {
+	before := string(line[:pos])
+
+	if strings.HasPrefix(before, ":") && !strings.ContainsAny(before, " \t") {
+		names := []string{}
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return candidates(before[1:], names)
+	}
+
+	start := len(before)
+	for start > 0 && (isIdentifierByte(before[start-1]) || before[start-1] == '.') {
+		start--
+	}
+
+	path := strings.Split(before[start:], ".")
+	prefix := path[len(path)-1]
+
+	if len(path) == 1 {
+		return candidates(prefix, ctx.Names())
+	}
+
+	value, err := ctx.GetKey(path[0])
+	if err != nil {
+		return nil, 0
+	}
+
+	for _, key := range path[1 : len(path)-1] {
+		m, ok := value.(*ergolas.Map)
+		if !ok {
+			return nil, 0
+		}
+
+		if value, ok = m.Get(key); !ok {
+			return nil, 0
+		}
+	}
+
+	m, ok := value.(*ergolas.Map)
+	if !ok {
+		return nil, 0
+	}
+
+	return candidates(prefix, m.Keys())
+}
+
+// candidates returns the suffixes of the names starting with the given prefix
+// in the format expected by readline
+func candidates(prefix string, names []string) ([][]rune, int) {
+	result := [][]rune{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, []rune(name[len(prefix):]))
+		}
+	}
+
+	return result, len([]rune(prefix))
+}
+
+func isIdentifierByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '$'
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"github.com/aziis98/ergolas"
+)
+
+func TestCompleter(t *testing.T) {
+	utils := ergolas.NewMap()
+	utils.Set("shout", nil)
+	utils.Set("show", nil)
+	utils.Set("nested", ergolas.NewMap())
+
+	ctx = root.Fork(ergolas.WithBindings(map[string]any{
+		"utils":    utils,
+		"printer":  nil,
+		"a-number": int64(1),
+	}))
+	defer func() { ctx = root.Fork() }()
+
+	tests := map[string]string{
+		`print`:          "print println printer printf printfln",
+		`x := a-nu`:      "a-number",
+		`utils.sh`:       "shout show",
+		`(upper utils.n`: "nested",
+		`utils.nested.`:  "",
+		`a-number.`:      "",
+		`:lo`:            "load",
+		`:ast o`:         "",
+	}
+
+	for input, expected := range tests {
+		suffixes, length := completer{}.Do([]rune(input+" rest"), len(input))
+
+		names := []string{}
+		for _, suffix := range suffixes {
+			names = append(names, input[len(input)-length:]+string(suffix))
+		}
+
+		sort.Strings(names)
+		sortedExpected := strings.Fields(expected)
+		sort.Strings(sortedExpected)
+
+		if strings.Join(names, " ") != strings.Join(sortedExpected, " ") {
+			t.Errorf("%q: expected %v, got %v", input, sortedExpected, names)
+		}
+	}
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func TestHighlight(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	for _, input := range []string{
+		`f := fn x { x + 1.5 } # comment`,
+		`println "unterminated`,
+		`:quoted $unquoted`,
+		`1 ? 2`,
+	} {
+		painted := highlight(input)
+		if painted == input {
+			t.Errorf("%q: expected some colors", input)
+		}
+		if stripped := ansiEscape.ReplaceAllString(painted, ""); stripped != input {
+			t.Errorf("%q: highlighting changed the text to %q", input, stripped)
+		}
+	}
+
+	if painted := highlight(`"hi"`); painted != tokenColors[ergolas.StringToken].Sprint(`"hi"`) {
+		t.Errorf("expected a colored string, got %q", painted)
+	}
+}
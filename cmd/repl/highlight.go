@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/aziis98/ergolas"
+)
+
+// tokenColors are the colors of the tokens in the input line, the tokens
+// missing here are not colored
+var tokenColors = map[ergolas.TokenType]*color.Color{
+	ergolas.IntegerToken:   color.New(color.FgCyan),
+	ergolas.FloatToken:     color.New(color.FgCyan),
+	ergolas.DurationToken:  color.New(color.FgCyan),
+	ergolas.StringToken:    color.New(color.FgGreen),
+	ergolas.RegexToken:     color.New(color.FgYellow),
+	ergolas.QuoteToken:     color.New(color.FgMagenta),
+	ergolas.UnquoteToken:   color.New(color.FgMagenta),
+	ergolas.LOperatorToken: color.New(color.FgYellow),
+	ergolas.ROperatorToken: color.New(color.FgYellow),
+	ergolas.CommentToken:   color.New(color.FgHiBlack, color.Italic),
+}
+
+var keywordColor = color.New(color.FgMagenta, color.Bold)
+
+// highlighter colors the input line as it gets typed
+type highlighter struct{}
+
+func (highlighter) Paint(line []rune, pos int) []rune {
+	return []rune(highlight(string(line)))
+}
+
+// highlight colors the tokens of the source, the part after a tokenizer error
+// is left as is but for an unterminated string that gets colored as a string
+func highlight(source string) string {
+	tokens, err := ergolas.TokenizeWithTrivia(source)
+	if err != nil {
+		var tokErr ergolas.TokenizeError
+		if !errors.As(err, &tokErr) {
+			return source
+		}
+
+		rest := source[tokErr.Location:]
+		if rest[0] == '"' {
+			rest = tokenColors[ergolas.StringToken].Sprint(rest)
+		}
+
+		return highlight(source[:tokErr.Location]) + rest
+	}
+
+	sb := &strings.Builder{}
+	for _, t := range tokens {
+		if t.Type == ergolas.IdentifierToken && t.Value == "fn" {
+			sb.WriteString(keywordColor.Sprint(t.Value))
+		} else if c, ok := tokenColors[t.Type]; ok {
+			sb.WriteString(c.Sprint(t.Value))
+		} else {
+			sb.WriteString(t.Value)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/aziis98/ergolas"
+)
+
+// isIncomplete tells whether the input ends too early to be evaluated and the
+// user should be asked for more lines, this happens with unbalanced brackets,
+// unterminated strings or a trailing operator.
+func isIncomplete(input string) bool {
+	tokens, err := ergolas.Tokenize(input)
+	if err != nil {
+		// a string without the closing quote is the only thing the tokenizer
+		// rejects that can be fixed by more input
+		var tokErr ergolas.TokenizeError
+		return errors.As(err, &tokErr) && input[tokErr.Location] == '"'
+	}
+
+	depth := 0
+	for _, t := range tokens {
+		if t.Type != ergolas.PunctuationToken {
+			continue
+		}
+
+		switch t.Value {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		}
+	}
+	if depth > 0 {
+		return true
+	}
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch t := tokens[i]; t.Type {
+		case ergolas.NewlineToken:
+			continue
+		case ergolas.LOperatorToken, ergolas.ROperatorToken, ergolas.QuoteToken, ergolas.UnquoteToken:
+			return true
+		case ergolas.PunctuationToken:
+			return t.Value == "." || t.Value == ","
+		default:
+			return false
+		}
+	}
+
+	return false
+}
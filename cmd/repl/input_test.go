@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestIsIncomplete(t *testing.T) {
+	tests := map[string]bool{
+		`println "hi"`:               false,
+		`f := fn x {`:                true,
+		"f := fn x {\n  x + 1\n}":    false,
+		"if (a > 1) {\n":             true,
+		`(1 + 2`:                     true,
+		`1 +`:                        true,
+		"x :=\n":                     true,
+		`a.`:                         true,
+		`println "unterminated`:      true,
+		`1 + 2)`:                     false,
+		`# just a comment`:           false,
+		"x := 1 # with a comment {":  false,
+		"f := fn x { # comment\n  x": true,
+	}
+
+	for input, expected := range tests {
+		if actual := isIncomplete(input); actual != expected {
+			t.Errorf("%q: expected %v, got %v", input, expected, actual)
+		}
+	}
+}
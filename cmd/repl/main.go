@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/repr"
 	"github.com/chzyer/readline"
@@ -12,71 +20,174 @@ import (
 )
 
 // ctx is the main repl evaluation context. This is a global as this is just a small experimental repl and this way I don't need to pass the context thorough every function call.
-var ctx = ergolas.NewRootContext()
+var ctx = root.Fork()
+
+// root holds the builtins, the bindings defined in the repl go in ctx so ":reset" can just fork it again
+var root = ergolas.NewRootContext(
+	ergolas.WithModules(os.DirFS(".")),
+	ergolas.WithFS("."),
+	ergolas.WithEnv(os.LookupEnv),
+	ergolas.WithExec("."),
+)
 
 func init() {
 	log.SetFlags(log.Lshortfile | log.Lmsgprefix)
 }
 
 func main() {
+	historyFile := flag.String("history", defaultHistoryFile(), "file where the input history is saved, empty to disable it")
+	flag.Parse()
+
 	color.Set(color.Italic)
 	fmt.Println()
 	fmt.Println("My Lang REPL")
-	fmt.Println("Type 'exit <number>' or press Ctrl+C to quit.")
+	fmt.Println("Type 'exit <number>' or press Ctrl+D to quit, Ctrl+C cancels the current input.")
+	fmt.Println("Type ':help' for the list of commands.")
 	fmt.Println()
 	color.Unset()
 
-	rl, err := readline.New(color.YellowString("> "))
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          prompt,
+		HistoryFile:     *historyFile,
+		InterruptPrompt: "^C",
+		AutoComplete:    completer{},
+		Painter:         highlighter{},
+	})
 	if err != nil {
 		panic(err)
 	}
 	defer rl.Close()
 
+	// buffer holds the lines of an input that is not complete yet
+	buffer := ""
+
 	for {
+		if buffer == "" {
+			rl.SetPrompt(prompt)
+		} else {
+			rl.SetPrompt(continuationPrompt)
+		}
+
 		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			buffer = ""
+			continue
+		}
 		if err != nil {
 			break
 		}
 
-		processInput(line)
+		if buffer != "" {
+			buffer += "\n"
+		}
+		buffer += line
+
+		if isIncomplete(buffer) {
+			continue
+		}
+
+		input := buffer
+		buffer = ""
+
+		if strings.TrimSpace(input) != "" {
+			processInput(input)
+		}
+	}
+}
+
+var (
+	prompt             = color.YellowString("> ")
+	continuationPrompt = color.YellowString(". ")
+)
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
 	}
+
+	return filepath.Join(home, ".ergolas_history")
 }
 
 func processInput(input string) {
+	if cmd, args, ok := parseCommand(input); ok {
+		if err := cmd.run(args); err != nil {
+			log.Printf("error: %v (usage: %s)", err, cmd.usage)
+		}
+
+		return
+	}
+
 	tokens, err := ergolas.Tokenize(input)
 	if err != nil {
 		log.Printf("error: %v", err)
 		return
 	}
 
+	if settings.tokens {
+		color.Set(color.FgMagenta)
+		fmt.Println("---< Tokens >---")
+		for _, t := range tokens {
+			fmt.Printf("%-12s %q\n", t.Type, t.Value)
+		}
+		color.Unset()
+	}
+
 	node, err := ergolas.ParseExpressions(tokens)
 	if err != nil {
 		log.Printf("error: %v", err)
 		return
 	}
 
-	color.Set(color.FgBlue)
-	fmt.Println("---< AST >---")
-	ergolas.PrintAST(node)
-	color.Unset()
+	if settings.ast {
+		color.Set(color.FgBlue)
+		fmt.Println("---< AST >---")
+		ergolas.PrintAST(node)
+		color.Unset()
+	}
 
 	color.Set(color.FgWhite)
 	fmt.Println("---< Output >---")
-	result, err := ergolas.EvaluateWith(node, ctx)
+	// Ctrl+C while evaluating interrupts the script instead of the repl
+	interrupt, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	result, err := ergolas.EvaluateWithOptions(node, ctx, ergolas.EvalOptions{Context: interrupt})
+	elapsed := time.Since(start)
+	color.Unset()
+
 	if err != nil {
+		var exitErr ergolas.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		log.Printf("error: %v", err)
 		return
 	}
-	color.Unset()
-
-	// color.Set(color.FgHiYellow)
-	// fmt.Println("---< New Context >---")
-	// fmt.Println(repr.String(ctx, repr.Indent("  ")))
-	// color.Unset()
 
 	color.Set(color.FgGreen)
 	fmt.Println("---< Result >---")
-	fmt.Println(repr.String(result, repr.Indent("  ")))
+	fmt.Println(formatValue(result))
 	color.Unset()
 
+	if settings.time {
+		color.Set(color.FgHiBlack)
+		fmt.Printf("took %v\n", elapsed)
+		color.Unset()
+	}
+}
+
+// formatValue formats a value for the repl, values with a String method like
+// script functions are shown with it instead of dumping their internals
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case fmt.Stringer:
+		return v.String()
+	case ergolas.Builtin, func(args ...any) (any, error):
+		return "<builtin>"
+	}
+
+	return repr.String(v, repr.Indent("  "))
 }
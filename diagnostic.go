@@ -0,0 +1,50 @@
+package ergolas
+
+import "fmt"
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Diagnostic is a problem found by a static analysis pass, located by the span
+// of source it refers to
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf(`%s: %s`, d.Severity, d.Message)
+}
+
+// Format returns the diagnostic prefixed by its line and column in the given source
+func (d Diagnostic) Format(source string) string {
+	line, col := computeLineColumn(source, d.Span.Start)
+	return fmt.Sprintf(`[%d:%d] %s: %s`, line, col, d.Severity, d.Message)
+}
+
+// HasErrors tells whether some of the given diagnostics is an error
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
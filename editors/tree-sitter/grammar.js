@@ -0,0 +1,41 @@
+// Code generated by cmd/ergolas-grammars from the tokenizer rules; DO NOT EDIT.
+
+module.exports = grammar({
+  name: 'ergolas',
+
+  extras: $ => [$.comment, $.whitespace],
+
+  word: $ => $.identifier,
+
+  rules: {
+    program: $ => seq(repeat($.newline), repeat(seq($._expression, optional(';'), repeat($.newline)))),
+    function_call: $ => prec.left(1, seq($._property_or_value, repeat1(seq($._argument, optional(','))))),
+    binary: $ => choice(prec.right(0, seq($._intermediate, $.r_operator, $._expression)), prec.left(2, seq($._argument, $.l_operator, $._property_or_value))),
+    unary: $ => seq($.r_operator, $._property_or_value),
+    quoted: $ => seq($.quote, $._property_or_value),
+    unquote_expression: $ => seq($.unquote, $._property_or_value),
+    property_access: $ => prec.left(3, seq($._property_or_value, '.', $.identifier)),
+    parenthesis: $ => seq('(', $._expression, ')'),
+    block: $ => seq('{', repeat($.newline), repeat(seq($._expression, optional(';'), repeat($.newline))), '}'),
+    list: $ => seq('[', repeat($.newline), repeat(seq($._argument, optional(','), repeat($.newline))), ']'),
+    _expression: $ => choice($.binary, $._intermediate),
+    _intermediate: $ => choice($.function_call, $._argument),
+    _argument: $ => choice(prec.left(2, seq($._argument, $.l_operator, $._property_or_value)), $._property_or_value),
+    _property_or_value: $ => choice($.property_access, $.parenthesis, $.block, $.list, $.identifier, $.integer, $.float, $.duration, $.string, $.regex, $.quoted, $.unquote_expression, $.unary),
+
+    duration: $ => token(prec(15, /(?:[0-9]+(?:\.[0-9]+)?(?:ns|us|ms|[hms]))+/)),
+    float: $ => token(prec(14, /[0-9]+\.[0-9]+/)),
+    integer: $ => token(prec(13, /[0-9]+/)),
+    string: $ => token(prec(12, /\"(?:\\.|[^"])*\"/)),
+    regex: $ => token(prec(11, /r\"(?:\\.|[^"])*\"/)),
+    r_operator: $ => token(choice(prec(10, /(?:\:[:=]|\<\-|\-\>|\|\>)/), prec(6, /\=/))),
+    quote: $ => token(prec(9, /\:/)),
+    unquote: $ => token(prec(8, /\$/)),
+    l_operator: $ => token(prec(7, /(?:[!%&*+\-\/<->\^|]{2,}|[!%&*+\-\/<>\^|])/)),
+    punctuation: $ => token(prec(5, /[(),.;\[\]{}]/)),
+    identifier: $ => token(prec(4, /[$\-A-Z_a-z][$\-0-9A-Z_a-z]*/)),
+    newline: $ => token(prec(3, /\n[\t\n\x0c\r ]*/)),
+    comment: $ => token(prec(2, /\#.*/)),
+    whitespace: $ => token(prec(1, /[\t ]+/)),
+  },
+});
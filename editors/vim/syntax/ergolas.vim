@@ -0,0 +1,35 @@
+" Code generated by cmd/ergolas-grammars from the tokenizer rules; DO NOT EDIT.
+
+if exists("b:current_syntax")
+  finish
+endif
+
+syntax match ergolasComment /\v\#.*/
+syntax match ergolasIdentifier /\v[$\-A-Z_a-z][$\-0-9A-Z_a-z]*/
+syntax match ergolasPunctuation /\v[(),.;[\]{}]/
+syntax match ergolasROperator /\v\=/
+syntax match ergolasLOperator /\v%([!%&*+\-\/<->\^|]{2,}|[!%&*+\-\/<>\^|])/
+syntax match ergolasUnquote /\v\$/
+syntax match ergolasQuote /\v\:/
+syntax match ergolasROperator /\v%(\:[:=]|\<\-|\-\>|\|\>)/
+syntax match ergolasRegex /\vr\"%(\\.|[^"])*\"/
+syntax match ergolasString /\v\"%(\\.|[^"])*\"/
+syntax match ergolasInteger /\v[0-9]+/
+syntax match ergolasFloat /\v[0-9]+\.[0-9]+/
+syntax match ergolasDuration /\v%([0-9]+%(\.[0-9]+)?%(ns|us|ms|[hms]))+%([$\-0-9A-Z_a-z])@!/
+
+highlight default link ergolasDuration Number
+highlight default link ergolasFloat Float
+highlight default link ergolasInteger Number
+highlight default link ergolasString String
+highlight default link ergolasRegex String
+highlight default link ergolasROperator Statement
+highlight default link ergolasQuote Special
+highlight default link ergolasUnquote Special
+highlight default link ergolasLOperator Operator
+highlight default link ergolasROperator Statement
+highlight default link ergolasPunctuation Delimiter
+highlight default link ergolasIdentifier Identifier
+highlight default link ergolasComment Comment
+
+let b:current_syntax = "ergolas"
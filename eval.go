@@ -1,18 +1,243 @@
 package ergolas
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Context is a scope of bindings, it is safe for concurrent use as long as
+// its Bindings are only accessed directly before any script runs in it.
 type Context struct {
 	Parent   *Context
 	Bindings map[string]any
+
+	mu sync.RWMutex
+	// frozen contexts reject new bindings and can be read without locking
+	frozen atomic.Bool
+	// consts are the names in Bindings declared with "const"
+	consts map[string]bool
+	// types are the types the names in Bindings were declared with, every
+	// value later bound to them must be of that type
+	types map[string]Type
+
+	// state tracks the limits of the current evaluation, see EvaluateWithOptions
+	state *evalState
+	// forward is set in the contexts created by evaluation, they have no
+	// bindings of their own and declare names in their parent
+	forward bool
+	// rng is the random source seeded by "seed" in this evaluation or fork,
+	// guarded by mu
+	rng *lockedRand
+
+	stdout, stderr io.Writer
+	stdin          *bufio.Reader
+
+	// modules loads the files imported by scripts, see WithModules
+	modules *moduleLoader
+	// imports is the chain of modules being imported ending with the one
+	// evaluated in this context, used to detect import cycles
+	imports []string
+}
+
+// Stdout returns the writer used by printing builtins, the nearest one set in
+// this context or its parents or the process stdout
+func (ctx *Context) Stdout() io.Writer {
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		if cur.stdout != nil {
+			return cur.stdout
+		}
+	}
+
+	return os.Stdout
+}
+
+// Stderr is like Stdout but for errors
+func (ctx *Context) Stderr() io.Writer {
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		if cur.stderr != nil {
+			return cur.stderr
+		}
+	}
+
+	return os.Stderr
+}
+
+// Stdin returns the reader used by input builtins, the nearest one set in
+// this context or its parents or the process stdin
+func (ctx *Context) Stdin() *bufio.Reader {
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		if cur.stdin != nil {
+			return cur.stdin
+		}
+	}
+
+	return processStdin
+}
+
+var processStdin = bufio.NewReader(os.Stdin)
+
+// child returns a new empty scope inside this context
+func (ctx *Context) child() *Context {
+	return &Context{Parent: ctx, Bindings: map[string]any{}, state: ctx.state}
+}
+
+// evaluation returns a context for evaluating a script in this one with its
+// own limits, so concurrent evaluations don't share them. Declarations made at
+// the top level of the script still end up in this context.
+func (ctx *Context) evaluation(opts EvalOptions) *Context {
+	return &Context{Parent: ctx, Bindings: map[string]any{}, state: &evalState{opts: opts}, forward: true}
+}
+
+// Fork freezes this context and returns a new child of it with the given
+// options applied, e.g. a host evaluating the same program for many requests
+// can fork a shared root for each of them with the request bindings.
+//
+//	root := ergolas.NewRootContext()
+//	req := root.Fork(ergolas.WithBindings(map[string]any{"path": r.URL.Path}))
+//	ergolas.EvaluateWith(program, req)
+//
+// Forking is cheap as no binding gets copied, assignments in the fork shadow
+// the bindings of the root without changing them.
+func (ctx *Context) Fork(opts ...ContextOption) *Context {
+	ctx.Freeze()
+
+	fork := &Context{Parent: ctx, Bindings: map[string]any{}}
+	for _, opt := range opts {
+		opt(fork)
+	}
+
+	return fork
+}
+
+// Freeze makes this context read-only, assigning a name in it gives an error
+func (ctx *Context) Freeze() {
+	ctx.frozen.Store(true)
+}
+
+// Set binds a value to a name in this context, this is the declaration
+// "name := value" and shadows the bindings of the parents
+func (ctx *Context) Set(name string, value any) error {
+	return ctx.declare(name, value, nil, false)
+}
+
+// SetConst is like Set but the binding can't be changed anymore, this is the
+// declaration "const name := value"
+func (ctx *Context) SetConst(name string, value any) error {
+	return ctx.declare(name, value, nil, true)
+}
+
+// SetTyped is like Set but the value and all the values later bound to the
+// name must be of the given type, this is the declaration
+// "name :: Type := value"
+func (ctx *Context) SetTyped(name string, value any, typ Type) error {
+	return ctx.declare(name, value, typ, false)
+}
+
+// declare binds a name in this context, a nil type keeps the type the name
+// was previously declared with in this context if any
+func (ctx *Context) declare(name string, value any, typ Type, constant bool) error {
+	if ctx.forward {
+		return ctx.Parent.declare(name, value, typ, constant)
+	}
+
+	if ctx.frozen.Load() {
+		return fmt.Errorf(`cannot assign "%s" in a frozen context`, name)
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if ctx.consts[name] {
+		return fmt.Errorf(`cannot assign to constant "%s"`, name)
+	}
+
+	if typ == nil {
+		typ = ctx.types[name]
+	}
+	if typ != nil {
+		if err := checkValueType(value, typ, fmt.Sprintf(`value for "%s"`, name)); err != nil {
+			return err
+		}
+		if ctx.types == nil {
+			ctx.types = map[string]Type{}
+		}
+
+		ctx.types[name] = typ
+	}
+
+	ctx.Bindings[name] = value
+	if constant {
+		if ctx.consts == nil {
+			ctx.consts = map[string]bool{}
+		}
+
+		ctx.consts[name] = true
+	}
+
+	return nil
+}
+
+// Assign changes the value of the nearest binding of a name in this context or
+// its parents, this is the assignment "name = value"
+func (ctx *Context) Assign(name string, value any) error {
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		if cur.frozen.Load() {
+			if _, ok := cur.Bindings[name]; ok {
+				return fmt.Errorf(`cannot assign "%s" in a frozen context`, name)
+			}
+
+			continue
+		}
+
+		if ok, err := cur.assign(name, value); ok {
+			return err
+		}
+	}
+
+	return fmt.Errorf(`cannot assign to undeclared variable "%s"`, name)
+}
+
+// assign changes the value of a name bound in this context, it tells whether
+// the name is bound here
+func (ctx *Context) assign(name string, value any) (bool, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if _, ok := ctx.Bindings[name]; !ok {
+		return false, nil
+	}
+	if ctx.consts[name] {
+		return true, fmt.Errorf(`cannot assign to constant "%s"`, name)
+	}
+	if typ, ok := ctx.types[name]; ok {
+		if err := checkValueType(value, typ, fmt.Sprintf(`value for "%s"`, name)); err != nil {
+			return true, err
+		}
+	}
+
+	ctx.Bindings[name] = value
+	return true, nil
 }
 
 func (ctx *Context) GetKey(name string) (any, error) {
-	value, ok := ctx.Bindings[name]
+	var value any
+	var ok bool
+	if ctx.frozen.Load() {
+		value, ok = ctx.Bindings[name]
+	} else {
+		ctx.mu.RLock()
+		value, ok = ctx.Bindings[name]
+		ctx.mu.RUnlock()
+	}
+
 	if !ok {
 		if ctx.Parent != nil {
 			return ctx.Parent.GetKey(name)
@@ -24,32 +249,35 @@ func (ctx *Context) GetKey(name string) (any, error) {
 	return value, nil
 }
 
-func NewRootContext() *Context {
-	return &Context{nil, map[string]any{
-		"exit": func(args ...any) (any, error) {
-			if len(args) != 1 {
-				return nil, fmt.Errorf(`expected 1 argument, got %v`, len(args))
+// Names returns the sorted list of all names bound in this context and its parents
+func (ctx *Context) Names() []string {
+	seen := map[string]struct{}{}
+	names := []string{}
+
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		cur.mu.RLock()
+		for name := range cur.Bindings {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				names = append(names, name)
 			}
+		}
+		cur.mu.RUnlock()
+	}
 
-			nExitCode, ok := args[0].(int)
-			if !ok {
-				return nil, fmt.Errorf(`expected integer but got %v`, nExitCode)
-			}
+	sort.Strings(names)
+	return names
+}
 
-			os.Exit(nExitCode)
-			return nil, nil
-		},
-		"println": func(args ...any) (any, error) {
-			for _, arg := range args {
-				fmt.Print(arg)
-			}
-			fmt.Println()
+// Types returns the runtime types of all values bound in this context and its parents
+func (ctx *Context) Types() map[string]Type {
+	types := map[string]Type{}
+	for _, name := range ctx.Names() {
+		value, _ := ctx.GetKey(name)
+		types[name] = TypeOf(value)
+	}
 
-			return nil, nil
-		},
-		"true":  true,
-		"false": false,
-	}}
+	return types
 }
 
 func isTruthy(v any) bool {
@@ -60,7 +288,73 @@ func isTruthy(v any) bool {
 	return v != nil
 }
 
+// isEqual compares two values, integers and floats are compared by value
+func isEqual(a, b any) (result bool) {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa == fb
+		}
+	}
+
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+
+	// values like functions are not comparable
+	defer func() {
+		if recover() != nil {
+			result = false
+		}
+	}()
+
+	return a == b
+}
+
+func compare(op string, a, b any) (any, error) {
+	cmp := 0
+
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return nil, fmt.Errorf(`cannot apply operator "%s" to types %T and %T`, op, a, b)
+		}
+
+		if fa < fb {
+			cmp = -1
+		} else if fa > fb {
+			cmp = +1
+		}
+	} else if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf(`cannot apply operator "%s" to types %T and %T`, op, a, b)
+		}
+
+		cmp = strings.Compare(sa, sb)
+	} else if c, ok := compareTime(a, b); ok {
+		cmp = c
+	} else {
+		return nil, fmt.Errorf(`cannot apply operator "%s" to types %T and %T`, op, a, b)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
 func eval(node Node, ctx *Context) (any, error) {
+	if err := ctx.state.step(); err != nil {
+		return nil, err
+	}
+
 	switch node.Type() {
 	case ProgramNode:
 		for _, n := range node.Children() {
@@ -83,6 +377,18 @@ func eval(node Node, ctx *Context) (any, error) {
 
 		return lastResult, nil
 	case FunctionCallNode:
+		if def, ok := functionLiteral(node); ok {
+			return makeFunction(def, ctx)
+		}
+		if subject, arms, ok := matchExpression(node); ok {
+			v, err := eval(subject, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return evalMatch(v, arms, ctx)
+		}
+
 		calleeAst := node.Children()[0]
 		argsAst := node.Children()[1:]
 
@@ -101,31 +407,128 @@ func eval(node Node, ctx *Context) (any, error) {
 			vArgs = append(vArgs, vArg)
 		}
 
-		fn, ok := vCallee.(func(args ...any) (any, error))
-		if !ok {
-			return nil, fmt.Errorf(`not a function: %v`, vCallee)
-		}
-
-		return fn(vArgs...)
+		return callFunction(ctx, vCallee, vArgs...)
 	case BinaryExpressionNode:
 		lhs := node.Children()[0]
 		op := node.Children()[1].Metadata()["Value"].(string)
 		rhs := node.Children()[2]
 
+		if def, ok := functionLiteral(node); ok {
+			return makeFunction(def, ctx)
+		}
+
 		if op == ":=" {
+			// the type stays nil for declarations without an annotation
+			var typ Type
+			if target, typeAst, ok := typeAnnotation(lhs); ok {
+				var err error
+				if typ, err = parseTypeExpression(typeAst); err != nil {
+					return nil, err
+				}
+
+				lhs = target
+			}
+
+			target, constant := constDeclaration(lhs)
+			if isDestructuring(target) {
+				vRhs, err := eval(rhs, ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				if typ != nil {
+					if err := checkValueType(vRhs, typ, "value"); err != nil {
+						return nil, err
+					}
+				}
+
+				return nil, destructure(target, vRhs, ctx, constant)
+			}
+			if target.Type() != IdentifierNode {
+				return nil, fmt.Errorf(`expected identifier on left side of assignment`)
+			}
+
+			name := target.Metadata()["Value"].(string)
+
+			vRhs, err := eval(rhs, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, ctx.declare(name, vRhs, typ, constant)
+		}
+		if op == "=" {
 			if lhs.Type() != IdentifierNode {
 				return nil, fmt.Errorf(`expected identifier on left side of assignment`)
 			}
 
-			name := lhs.Metadata()["Value"].(string)
+			vRhs, err := eval(rhs, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, ctx.Assign(lhs.Metadata()["Value"].(string), vRhs)
+		}
+		if op == "::" {
+			typ, err := parseTypeExpression(rhs)
+			if err != nil {
+				return nil, err
+			}
+
+			vLhs, err := eval(lhs, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := checkValueType(vLhs, typ, "value"); err != nil {
+				return nil, err
+			}
+
+			return vLhs, nil
+		}
+		if op == "|>" {
+			vLhs, err := eval(lhs, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			calleeAst, argsAst, at := pipeCall(rhs)
+
+			vCallee, err := eval(calleeAst, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			vArgs := []any{}
+			for _, argAst := range argsAst {
+				vArg, err := eval(argAst, ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				vArgs = append(vArgs, vArg)
+			}
+
+			vArgs = append(vArgs[:at], append([]any{vLhs}, vArgs[at:]...)...)
+			return callFunction(ctx, vCallee, vArgs...)
+		}
+		if op == "<-" {
+			vLhs, err := eval(lhs, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			c, err := expectChannel(vLhs)
+			if err != nil {
+				return nil, err
+			}
 
 			vRhs, err := eval(rhs, ctx)
 			if err != nil {
 				return nil, err
 			}
 
-			ctx.Bindings[name] = vRhs
-			return nil, nil
+			return nil, c.send(ctx, vRhs)
 		}
 		if op == "&&" {
 			vLhs, err := eval(lhs, ctx)
@@ -171,6 +574,10 @@ func eval(node Node, ctx *Context) (any, error) {
 			return nil, err
 		}
 
+		if result, ok, err := timeOperation(op, vLhs, vRhs); ok {
+			return result, err
+		}
+
 		switch op {
 		case "+":
 			if nLhs, ok := vLhs.(int64); ok {
@@ -185,6 +592,10 @@ func eval(node Node, ctx *Context) (any, error) {
 			}
 			if nLhs, ok := vLhs.(string); ok {
 				if nRhs, ok := vRhs.(string); ok {
+					if err := ctx.state.alloc(int64(len(nLhs) + len(nRhs))); err != nil {
+						return nil, err
+					}
+
 					return nLhs + nRhs, nil
 				}
 			}
@@ -219,6 +630,10 @@ func eval(node Node, ctx *Context) (any, error) {
 		case "/":
 			if nLhs, ok := vLhs.(int64); ok {
 				if nRhs, ok := vRhs.(int64); ok {
+					if nRhs == 0 {
+						return nil, fmt.Errorf(`division by zero`)
+					}
+
 					return nLhs / nRhs, nil
 				}
 			}
@@ -232,6 +647,10 @@ func eval(node Node, ctx *Context) (any, error) {
 		case "%":
 			if nLhs, ok := vLhs.(int64); ok {
 				if nRhs, ok := vRhs.(int64); ok {
+					if nRhs == 0 {
+						return nil, fmt.Errorf(`division by zero`)
+					}
+
 					return nLhs % nRhs, nil
 				}
 			}
@@ -242,13 +661,46 @@ func eval(node Node, ctx *Context) (any, error) {
 			}
 
 			return nil, fmt.Errorf(`cannot apply operator "%%" to types %T and %T`, vLhs, vRhs)
+		case "^":
+			return power(vLhs, vRhs)
+		case "==":
+			return isEqual(vLhs, vRhs), nil
+		case "!=":
+			return !isEqual(vLhs, vRhs), nil
+		case "<", "<=", ">", ">=":
+			return compare(op, vLhs, vRhs)
+		}
+
+		return nil, fmt.Errorf(`unknown operator "%s"`, op)
+
+	case UnaryExpressionNode:
+		// the only unary operator is the receive "<- c"
+		v, err := eval(node.Children()[1], ctx)
+		if err != nil {
+			return nil, err
 		}
 
+		c, err := expectChannel(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.recv(ctx)
+
 	case QuotedExpressionNode:
+		if inner := node.Children()[0]; inner.Type() == IdentifierNode {
+			return Intern(inner.Metadata()["Value"].(string)), nil
+		}
+
 		return node, nil
 
 	case PropertyAccessNode:
-		return nil, fmt.Errorf(`not implemented`)
+		v, err := eval(node.Children()[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return propertyOf(ctx, v, node.Children()[1].Metadata()["Value"].(string))
 
 	case ParenthesisNode:
 		return eval(node.Children()[0], ctx)
@@ -258,7 +710,24 @@ func eval(node Node, ctx *Context) (any, error) {
 		return ctx.GetKey(name)
 
 	case BlockNode:
-		return nil, fmt.Errorf(`not implemented`)
+		return makeFunction(&functionDef{Body: node}, ctx)
+
+	case ListNode:
+		if err := ctx.state.alloc(int64(len(node.Children())) * listItemAllocSize); err != nil {
+			return nil, err
+		}
+
+		items := []any{}
+		for _, itemAst := range node.Children() {
+			item, err := eval(itemAst, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, item)
+		}
+
+		return NewList(items...), nil
 
 	case IntegerNode:
 		return node.Metadata()["Value"], nil
@@ -266,8 +735,14 @@ func eval(node Node, ctx *Context) (any, error) {
 	case FloatNode:
 		return node.Metadata()["Value"], nil
 
+	case DurationNode:
+		return node.Metadata()["Value"], nil
+
 	case StringNode:
 		return node.Metadata()["Value"], nil
+
+	case RegexNode:
+		return node.Metadata()["Value"], nil
 	}
 
 	return nil, fmt.Errorf(`unexpected node %T`, node)
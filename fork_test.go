@@ -0,0 +1,160 @@
+package ergolas_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+const requestHandler = `
+	greeting := if (len name) > 5 { "Hello, " } { "Hi, " }
+	counter := counter + 1
+	render := fn s { (upper greeting) + s + suffix }
+	render name
+`
+
+func newSharedRoot() *ergolas.Context {
+	return ergolas.NewRootContext(ergolas.WithBindings(map[string]any{
+		"suffix":  "!",
+		"counter": int64(0),
+	}))
+}
+
+func ExampleContext_Fork() {
+	tokens, _ := ergolas.Tokenize(`greeting := "Hello, " + name; greeting`)
+	program, _ := ergolas.ParseExpressions(tokens)
+
+	root := ergolas.NewRootContext()
+
+	for _, name := range []string{"Alice", "Bob"} {
+		req := root.Fork(ergolas.WithBindings(map[string]any{"name": name}))
+
+		result, err := ergolas.EvaluateWith(program, req)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(result)
+	}
+
+	_, err := root.GetKey("greeting")
+	fmt.Println(err)
+
+	// Output:
+	// Hello, Alice
+	// Hello, Bob
+	// unbound variable "greeting"
+}
+
+func TestForkFrozenRoot(t *testing.T) {
+	root := newSharedRoot()
+	fork := root.Fork()
+
+	if err := root.Set("suffix", "?"); err == nil {
+		t.Errorf("expected an error assigning in a frozen context")
+	}
+
+	// assignments in the fork shadow the root
+	if _, err := evaluateIn(fork, `suffix := "?"; counter := counter + 1`); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := root.GetKey("suffix"); v != "!" {
+		t.Errorf("expected root binding to be unchanged, got %v", v)
+	}
+	if v, _ := fork.GetKey("counter"); v != int64(1) {
+		t.Errorf("expected fork counter to be 1, got %v", v)
+	}
+
+	// functions defined in the root still get their own scopes when called
+	// from forks
+	frozenFn := ergolas.NewRootContext()
+	if _, err := evaluateIn(frozenFn, `double := fn x { y := x * 2; y }`); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := evaluateIn(frozenFn.Fork(), `double 21`); err != nil || v != int64(42) {
+		t.Errorf("expected 42, got %v (%v)", v, err)
+	}
+
+	if _, err := evaluateIn(root, `x := 1`); err == nil || err.Error() != `cannot assign "x" in a frozen context` {
+		t.Errorf("expected frozen context error, got %v", err)
+	}
+}
+
+func TestSharedProgramConcurrent(t *testing.T) {
+	program, err := parseSource(requestHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := fmt.Sprint(program)
+
+	root := newSharedRoot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		name := strings.Repeat("x", i%10)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := root.Fork(ergolas.WithBindings(map[string]any{"name": name}))
+			result, err := ergolas.EvaluateWithOptions(program, req, ergolas.EvalOptions{MaxSteps: 10_000})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			prefix := "HI, "
+			if len(name) > 5 {
+				prefix = "HELLO, "
+			}
+			if result != prefix+name+"!" {
+				t.Errorf("unexpected result %q for %q", result, name)
+			}
+			if v, _ := req.GetKey("counter"); v != int64(1) {
+				t.Errorf("expected counter of each request to be 1, got %v", v)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if after := fmt.Sprint(program); after != before {
+		t.Errorf("program changed during evaluation")
+	}
+}
+
+func BenchmarkSharedProgram(b *testing.B) {
+	program, err := parseSource(requestHandler)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	root := newSharedRoot()
+	root.Freeze()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req := root.Fork(ergolas.WithBindings(map[string]any{"name": "gopher"}))
+			if _, err := ergolas.EvaluateWith(program, req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFork(b *testing.B) {
+	root := newSharedRoot()
+	root.Freeze()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			root.Fork()
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package ergolas
+
+import "strings"
+
+var (
+	openingBrackets = map[string]struct{}{"(": {}, "[": {}, "{": {}}
+	closingBrackets = map[string]struct{}{")": {}, "]": {}, "}": {}}
+)
+
+// FormatSource reformats the given source code keeping all comments. Each line
+// gets indented using the given indent string once for each bracket still
+// open, runs of blank lines are collapsed to a single one and spaces between
+// tokens are collapsed to a single space.
+func FormatSource(source string, indent string) (string, error) {
+	tokens, err := TokenizeWithTrivia(source)
+	if err != nil {
+		return "", err
+	}
+
+	sb := &strings.Builder{}
+	depth := 0
+	lineStart := true
+
+	for i, t := range tokens {
+		switch t.Type {
+		case NewlineToken:
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+				if strings.Count(t.Value, "\n") > 1 {
+					sb.WriteString("\n")
+				}
+			}
+
+			lineStart = true
+			continue
+		case WhitespaceToken:
+			if !lineStart && i+1 < len(tokens) && tokens[i+1].Type != NewlineToken {
+				sb.WriteString(" ")
+			}
+
+			continue
+		}
+
+		if _, ok := closingBrackets[t.Value]; ok && t.Type == PunctuationToken && depth > 0 {
+			depth--
+		}
+
+		if lineStart {
+			sb.WriteString(strings.Repeat(indent, depth))
+			lineStart = false
+		}
+
+		sb.WriteString(t.Value)
+
+		if _, ok := openingBrackets[t.Value]; ok && t.Type == PunctuationToken {
+			depth++
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n") + "\n", nil
+}
@@ -0,0 +1,350 @@
+package ergolas
+
+import (
+	"fmt"
+	"strings"
+)
+
+type functionParam struct {
+	Name string
+	Span Span
+
+	// Type is the type annotation of this parameter or nil
+	Type Node
+}
+
+type functionDef struct {
+	Params []functionParam
+
+	// Result is the type annotation of the returned value or nil
+	Result Node
+	Body   Node
+}
+
+func isIdentifier(node Node, name string) bool {
+	return node.Type() == IdentifierNode && node.Metadata()["Value"] == name
+}
+
+// functionLiteral matches one of the forms
+//
+//	fn <Param>* <Block>
+//	fn <Param>* :: <Type> <Block>
+//	<Param>+ -> <Expression>
+//	(<Param>+) -> <Expression>
+//
+// where <Param> is either an identifier or "(<Identifier> :: <Type>)".
+func functionLiteral(node Node) (*functionDef, bool) {
+	var head []Node
+	var result Node
+	var body Node
+
+	switch node.Type() {
+	case FunctionCallNode:
+		children := node.Children()
+		head = children[:len(children)-1]
+		body = children[len(children)-1]
+	case BinaryExpressionNode:
+		lhs, op, rhs := node.Children()[0], node.Children()[1], node.Children()[2]
+		if op.Metadata()["Value"] == "->" {
+			return arrowFunction(lhs, rhs)
+		}
+		if op.Metadata()["Value"] != "::" || rhs.Type() != FunctionCallNode || len(rhs.Children()) != 2 {
+			return nil, false
+		}
+
+		switch lhs.Type() {
+		case FunctionCallNode:
+			head = lhs.Children()
+		case IdentifierNode:
+			head = []Node{lhs}
+		default:
+			return nil, false
+		}
+
+		result = rhs.Children()[0]
+		body = rhs.Children()[1]
+	default:
+		return nil, false
+	}
+
+	if len(head) == 0 || !isIdentifier(head[0], "fn") || body.Type() != BlockNode {
+		return nil, false
+	}
+
+	def := &functionDef{Result: result, Body: body}
+	for _, p := range head[1:] {
+		param, ok := functionParameter(p)
+		if !ok {
+			return nil, false
+		}
+
+		def.Params = append(def.Params, param)
+	}
+
+	return def, true
+}
+
+// arrowFunction matches the parameters and the body of "x y -> x + y", a block
+// as body is used directly so "x -> { ... }" doesn't return a block
+func arrowFunction(lhs, rhs Node) (*functionDef, bool) {
+	head := []Node{lhs}
+	if lhs.Type() == ParenthesisNode {
+		if _, _, ok := typeAnnotation(lhs.Children()[0]); !ok {
+			lhs = lhs.Children()[0]
+		}
+	}
+	if lhs.Type() == FunctionCallNode {
+		head = lhs.Children()
+	} else if lhs.Type() == IdentifierNode {
+		head = []Node{lhs}
+	}
+
+	body := rhs
+	if body.Type() != BlockNode {
+		body = listNode{BlockNode, []Node{rhs}, rhs.Span()}
+	}
+
+	def := &functionDef{Body: body}
+	for _, p := range head {
+		param, ok := functionParameter(p)
+		if !ok {
+			return nil, false
+		}
+
+		def.Params = append(def.Params, param)
+	}
+
+	return def, true
+}
+
+// pipeCall returns the function and the arguments of the right side of a
+// pipe "x |> f a b", the piped value goes at the given index of the arguments
+// that is the position of the placeholder "_" if there is one or otherwise
+// the first one.
+func pipeCall(rhs Node) (callee Node, args []Node, at int) {
+	if rhs.Type() != FunctionCallNode || IsFunctionLiteral(rhs) {
+		return rhs, nil, 0
+	}
+
+	placeholder := -1
+	for i, arg := range rhs.Children()[1:] {
+		if placeholder < 0 && isIdentifier(arg, "_") {
+			placeholder = i
+			continue
+		}
+
+		args = append(args, arg)
+	}
+
+	if placeholder < 0 {
+		return rhs.Children()[0], args, 0
+	}
+
+	return rhs.Children()[0], args, placeholder
+}
+
+// IsFunctionLiteral tells whether the given node is a function definition
+// like "fn x y { x + y }" or a block
+func IsFunctionLiteral(node Node) bool {
+	if node.Type() == BlockNode {
+		return true
+	}
+
+	_, ok := functionLiteral(node)
+	return ok
+}
+
+func functionParameter(node Node) (functionParam, bool) {
+	if node.Type() == IdentifierNode {
+		return functionParam{Name: node.Metadata()["Value"].(string), Span: node.Span()}, true
+	}
+
+	if node.Type() != ParenthesisNode {
+		return functionParam{}, false
+	}
+
+	name, typ, ok := typeAnnotation(node.Children()[0])
+	if !ok || name.Type() != IdentifierNode {
+		return functionParam{}, false
+	}
+
+	return functionParam{Name: name.Metadata()["Value"].(string), Span: name.Span(), Type: typ}, true
+}
+
+// constDeclaration matches "const <Expression>" on the left side of a
+// declaration and returns the declared expression, otherwise the node itself
+func constDeclaration(node Node) (Node, bool) {
+	if node.Type() != FunctionCallNode || len(node.Children()) != 2 || !isIdentifier(node.Children()[0], "const") {
+		return node, false
+	}
+
+	return node.Children()[1], true
+}
+
+// typeAnnotation matches "<Expression> :: <Type>"
+func typeAnnotation(node Node) (expr, typ Node, ok bool) {
+	if node.Type() != BinaryExpressionNode || node.Children()[1].Metadata()["Value"] != "::" {
+		return nil, nil, false
+	}
+
+	return node.Children()[0], node.Children()[2], true
+}
+
+func evalBody(body Node, ctx *Context) (any, error) {
+	var lastResult any
+
+	for _, n := range body.Children() {
+		var err error
+		if lastResult, err = eval(n, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return lastResult, nil
+}
+
+// Function is a closure defined by a script with "fn" or a block
+type Function struct {
+	def *functionDef
+	ctx *Context
+
+	paramTypes []Type
+	resultType Type
+}
+
+// Type returns the static type of the function built from its annotations
+func (f *Function) Type() FunctionType {
+	return FunctionType{f.paramTypes, f.resultType}
+}
+
+func (f *Function) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprint(sb, "<fn")
+	for i, p := range f.def.Params {
+		fmt.Fprintf(sb, " %s", p.Name)
+		if f.paramTypes[i] != AnyType {
+			fmt.Fprintf(sb, " :: %v", f.paramTypes[i])
+		}
+	}
+	fmt.Fprint(sb, ">")
+	return sb.String()
+}
+
+// Call calls the function from Go, each call has the same limits as the
+// evaluation the function was defined in but counts its resources from
+// zero. The Go context of that evaluation isn't used, so a closure can be
+// called after the evaluation that created it is cancelled. Use the Call
+// function to call it from a Builtin, or Interpreter.CallValue to choose
+// the limits.
+func (f *Function) Call(args ...any) (any, error) {
+	opts := EvalOptions{}
+	if f.ctx.state != nil {
+		opts = f.ctx.state.opts
+		opts.Context = nil
+	}
+
+	return f.call(f.ctx.evaluation(opts), args...)
+}
+
+// call evaluates the body of the function in a new scope of the context it
+// was defined in, while the limits are the ones of the caller
+func (f *Function) call(caller *Context, args ...any) (any, error) {
+	if len(args) != len(f.def.Params) {
+		return nil, fmt.Errorf(`expected %d arguments, got %d`, len(f.def.Params), len(args))
+	}
+
+	local := &Context{Parent: f.ctx, Bindings: map[string]any{}, state: caller.state}
+
+	if err := local.state.alloc(scopeAllocSize); err != nil {
+		return nil, err
+	}
+	if err := local.state.enter(); err != nil {
+		return nil, err
+	}
+	defer local.state.leave()
+
+	for i, p := range f.def.Params {
+		if err := checkValueType(args[i], f.paramTypes[i], fmt.Sprintf(`argument "%s"`, p.Name)); err != nil {
+			return nil, err
+		}
+
+		local.Bindings[p.Name] = args[i]
+		if p.Type != nil {
+			if local.types == nil {
+				local.types = map[string]Type{}
+			}
+
+			// the parameter keeps its type when assigned in the body
+			local.types[p.Name] = f.paramTypes[i]
+		}
+	}
+
+	result, err := evalBody(f.def.Body, local)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkValueType(result, f.resultType, "result"); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// makeFunction creates a closure over the given context, parameters and
+// results with a type annotation are checked on each call
+func makeFunction(def *functionDef, ctx *Context) (*Function, error) {
+	paramTypes := make([]Type, len(def.Params))
+	for i, p := range def.Params {
+		paramTypes[i] = AnyType
+		if p.Type != nil {
+			typ, err := parseTypeExpression(p.Type)
+			if err != nil {
+				return nil, err
+			}
+
+			paramTypes[i] = typ
+		}
+	}
+
+	var resultType Type = AnyType
+	if def.Result != nil {
+		typ, err := parseTypeExpression(def.Result)
+		if err != nil {
+			return nil, err
+		}
+
+		resultType = typ
+	}
+
+	if err := ctx.state.alloc(functionAllocSize); err != nil {
+		return nil, err
+	}
+
+	return &Function{def, ctx, paramTypes, resultType}, nil
+}
+
+// callFunction calls a script function or a builtin with the given arguments
+func callFunction(ctx *Context, fn any, args ...any) (any, error) {
+	switch fn := fn.(type) {
+	case *Function:
+		return fn.call(ctx, args...)
+	case func(args ...any) (any, error):
+		return fn(args...)
+	case Builtin:
+		return fn(ctx, args...)
+	}
+
+	return nil, fmt.Errorf(`not a function: %v`, fn)
+}
+
+// callIfFunction calls the given value without arguments if it is a function
+// or otherwise just returns it
+func callIfFunction(ctx *Context, v any) (any, error) {
+	switch v.(type) {
+	case *Function, func(args ...any) (any, error), Builtin:
+		return callFunction(ctx, v)
+	}
+
+	return v, nil
+}
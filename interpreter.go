@@ -0,0 +1,172 @@
+package ergolas
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// Interpreter is the embedding API for running scripts inside a host
+// application. Scripts share the global bindings of the interpreter so a
+// script can be loaded once and its functions called later as hooks.
+//
+//	in := ergolas.NewInterpreter(ergolas.WithCore(), ergolas.WithIO())
+//	in.RunString(`on-request := fn (path :: String) { println "got " path }`)
+//	in.Call("on-request", "/index.html")
+//
+// An Interpreter is not safe for concurrent use.
+type Interpreter struct {
+	// Options are the limits enforced on each run or call
+	Options EvalOptions
+
+	globals *Context
+}
+
+// NewInterpreter creates an interpreter whose globals are a root context
+// created with the given options, see NewContext.
+func NewInterpreter(opts ...ContextOption) *Interpreter {
+	return &Interpreter{globals: NewContext(opts...)}
+}
+
+// Globals returns the root context of the interpreter
+func (in *Interpreter) Globals() *Context {
+	return in.globals
+}
+
+// RunString evaluates the given source code in the globals of the
+// interpreter and returns the value of its last expression
+func (in *Interpreter) RunString(source string) (any, error) {
+	tokens, err := Tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := ParseExpressions(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return EvaluateWithOptions(node, in.globals, in.Options)
+}
+
+// RunFile is like RunString but reads the source code from a file
+func (in *Interpreter) RunFile(path string) (any, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := in.RunString(string(source))
+	if err != nil {
+		return nil, fmt.Errorf(`%s: %w`, path, err)
+	}
+
+	return result, nil
+}
+
+// Get returns the value of a global binding
+func (in *Interpreter) Get(name string) (any, error) {
+	return in.globals.GetKey(name)
+}
+
+// Set binds a Go value to a global name, the value is converted with ToValue
+func (in *Interpreter) Set(name string, value any) error {
+	v, err := ToValue(value)
+	if err != nil {
+		return err
+	}
+
+	return in.globals.Set(name, v)
+}
+
+// Call calls the global function with the given name
+func (in *Interpreter) Call(name string, args ...any) (any, error) {
+	fn, err := in.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return in.CallValue(fn, args...)
+}
+
+// CallValue calls a function value, e.g. a closure received by a Go builtin
+// from a script, enforcing the limits of the interpreter
+func (in *Interpreter) CallValue(fn any, args ...any) (any, error) {
+	return Call(in.globals.evaluation(in.Options), fn, args...)
+}
+
+// Call calls a script function or a builtin from Go, the arguments are
+// converted with ToValue. The context is passed to builtins and can be any
+// context of the script, e.g. the one received by a Builtin.
+func Call(ctx *Context, fn any, args ...any) (any, error) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		v, err := ToValue(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = v
+	}
+
+	return callFunction(ctx, fn, values...)
+}
+
+// ToValue converts a Go value to the representation used by scripts, i.e.
+// all integers become int64, floats become float64 and slices become lists.
+// Values without a conversion are returned unchanged, unsigned integers
+// larger than the largest Int are an error.
+func ToValue(v any) (any, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		return uintValue(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uintValue(v)
+	case float32:
+		return float64(v), nil
+	case func(ctx *Context, args ...any) (any, error):
+		return Builtin(v), nil
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return NewList(items...), nil
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			value, err := ToValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = value
+		}
+		return NewList(items...), nil
+	}
+
+	return v, nil
+}
+
+// uintValue converts an unsigned integer that must fit in an Int
+func uintValue(v uint64) (any, error) {
+	if v > math.MaxInt64 {
+		return nil, fmt.Errorf(`integer %d overflows Int`, v)
+	}
+
+	return int64(v), nil
+}
@@ -0,0 +1,159 @@
+package ergolas_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleInterpreter() {
+	in := ergolas.NewInterpreter(ergolas.WithCore(), ergolas.WithIO())
+
+	if _, err := in.RunString(`on-request := fn (path :: String) { println "got " path }`); err != nil {
+		panic(err)
+	}
+
+	if _, err := in.Call("on-request", "/index.html"); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// got /index.html
+}
+
+func ExampleInterpreter_Set() {
+	in := ergolas.NewInterpreter(ergolas.WithCore())
+	in.Set("limit", 10)
+
+	in.RunString(`double-limit := limit * 2`)
+
+	v, _ := in.Get("double-limit")
+	fmt.Printf("%v %T\n", v, v)
+
+	// Output:
+	// 20 int64
+}
+
+func ExampleCall() {
+	// a host callback receiving a closure from the script
+	each := func(ctx *ergolas.Context, args ...any) (any, error) {
+		for i := 1; i <= 3; i++ {
+			if _, err := ergolas.Call(ctx, args[0], i); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	in := ergolas.NewInterpreter(ergolas.WithCore(), ergolas.WithIO())
+	in.Set("each", each)
+
+	if _, err := in.RunString(`each (fn i { println "item " i })`); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// item 1
+	// item 2
+	// item 3
+}
+
+func TestInterpreterRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.erg")
+	if err := os.WriteFile(path, []byte("square := fn x :: Int { x * x }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	in := ergolas.NewInterpreter(ergolas.WithCore())
+	if _, err := in.RunFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := in.Call("square", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != int64(49) {
+		t.Fatalf("expected 49, got %v", v)
+	}
+
+	if _, err := in.Call("square", "seven"); err == nil {
+		t.Fatalf("expected a type error")
+	}
+	if _, err := in.Call("missing"); err == nil {
+		t.Fatalf("expected an error for an undefined function")
+	}
+}
+
+func TestInterpreterCallLimits(t *testing.T) {
+	in := ergolas.NewInterpreter(ergolas.WithCore())
+	if _, err := in.RunString(`loop := fn x { loop x }`); err != nil {
+		t.Fatal(err)
+	}
+
+	// limits are set after the function is defined and still apply to it
+	in.Options = ergolas.EvalOptions{MaxDepth: 100}
+
+	_, err := in.Call("loop", 1)
+
+	var limitErr ergolas.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.DepthLimit {
+		t.Fatalf("expected depth limit error, got %v", err)
+	}
+}
+
+func TestFunctionCallLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	root := ergolas.NewRootContext()
+	v, err := evaluateIn(root, `double := fn x { x * 2 }; double`, ergolas.EvalOptions{Context: ctx, MaxSteps: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	// each call from the host starts counting from zero and isn't stopped
+	// by the evaluation that created the function
+	fn := v.(*ergolas.Function)
+	for i := 0; i < 100; i++ {
+		if result, err := fn.Call(int64(i)); err != nil || result != int64(i*2) {
+			t.Fatalf("call %d: expected %d, got %v (%v)", i, i*2, result, err)
+		}
+	}
+
+	v, err = evaluateIn(root, `loop := fn x { loop x }; loop`, ergolas.EvalOptions{MaxSteps: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.(*ergolas.Function).Call(int64(1))
+
+	var limitErr ergolas.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.StepLimit {
+		t.Fatalf("expected step limit error, got %v", err)
+	}
+}
+
+func TestToValueUnsigned(t *testing.T) {
+	if v, err := ergolas.ToValue(uint64(math.MaxInt64)); err != nil || v != int64(math.MaxInt64) {
+		t.Errorf("expected %d, got %v (%v)", int64(math.MaxInt64), v, err)
+	}
+
+	for _, v := range []any{uint64(math.MaxInt64) + 1, uint64(math.MaxUint64 - 4), []any{uint64(math.MaxUint64)}} {
+		if _, err := ergolas.ToValue(v); err == nil {
+			t.Errorf("%v: expected overflow error", v)
+		}
+	}
+
+	in := ergolas.NewInterpreter()
+	if err := in.Set("big", uint64(math.MaxUint64)); err == nil || err.Error() != `integer 18446744073709551615 overflows Int` {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package ergolas
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
@@ -26,15 +28,30 @@ func (nm NodeMetadata) String() string {
 	return sb.String()
 }
 
+// Span is the range of byte offsets [Start, End) of the source a node was parsed from
+type Span struct {
+	Start, End int
+}
+
+// Contains tells whether the given offset is inside this span
+func (s Span) Contains(offset int) bool {
+	return s.Start <= offset && offset < s.End
+}
+
+// Node is a node of the syntax tree, nodes are never modified after parsing
+// so a tree can be shared and evaluated by many goroutines at once. The
+// slices returned by Children must not be modified.
 type Node interface {
 	Type() NodeType
 	Children() []Node
 	Metadata() NodeMetadata
+	Span() Span
 }
 
 type listNode struct {
 	typ      NodeType
 	children []Node
+	span     Span
 }
 
 func (n listNode) Type() NodeType {
@@ -49,9 +66,18 @@ func (n listNode) Metadata() NodeMetadata {
 	return NodeMetadata{}
 }
 
+func (n listNode) Span() Span {
+	return n.span
+}
+
+func (n listNode) String() string {
+	return fmt.Sprintf("{%v %v}", n.typ, n.children)
+}
+
 type leafNode struct {
 	typ   NodeType
 	value any
+	span  Span
 }
 
 func (n leafNode) Type() NodeType {
@@ -66,23 +92,36 @@ func (n leafNode) Metadata() NodeMetadata {
 	return NodeMetadata{"Value": n.value}
 }
 
+func (n leafNode) Span() Span {
+	return n.span
+}
+
+func (n leafNode) String() string {
+	return fmt.Sprintf("{%v %v}", n.typ, n.value)
+}
+
 func PrintAST(node Node) {
-	printAST(node, 0)
+	FprintAST(os.Stdout, node)
+}
+
+// FprintAST is like PrintAST but writes to the given writer
+func FprintAST(w io.Writer, node Node) {
+	printAST(w, node, 0)
 }
 
-func printAST(node Node, depth int) {
+func printAST(w io.Writer, node Node, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s- %s", indent, node.Type())
+	fmt.Fprintf(w, "%s- %s", indent, node.Type())
 
 	meta := node.Metadata()
 	if len(meta) > 0 {
-		fmt.Printf(" { %s }\n", meta)
+		fmt.Fprintf(w, " { %s }\n", meta)
 	} else {
-		fmt.Printf("\n")
+		fmt.Fprintf(w, "\n")
 	}
 
 	for _, n := range node.Children() {
-		printAST(n, depth+1)
+		printAST(w, n, depth+1)
 	}
 }
 
@@ -303,7 +303,72 @@ func ExampleEvaluate_conditionals() {
 	// >>> false ~ false
 	// >>> false ~ false
 	// >>> false ~ false
-	// >>> {Quoted [{Identifier example}]} ~ :example
+	// >>> :example ~ :example
 	// >>> true ~ true
 
 }
+
+func ExampleParse_pipeline() {
+	tokens, err := ergolas.Tokenize(`xs |> map (x -> x * 2) |> join ", " _`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, err := ergolas.ParseExpression(tokens)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ergolas.PrintAST(node)
+
+	// Output:
+	// - Binary
+	//   - Binary
+	//     - Identifier { Value: "xs" }
+	//     - Operator { Value: "|>" }
+	//     - FunctionCall
+	//       - Identifier { Value: "map" }
+	//       - Parenthesis
+	//         - Binary
+	//           - Identifier { Value: "x" }
+	//           - Operator { Value: "->" }
+	//           - Binary
+	//             - Identifier { Value: "x" }
+	//             - Operator { Value: "*" }
+	//             - Integer { Value: "2" }
+	//   - Operator { Value: "|>" }
+	//   - FunctionCall
+	//     - Identifier { Value: "join" }
+	//     - String { Value: ", " }
+	//     - Identifier { Value: "_" }
+}
+
+func ExampleEvaluate_pipeline() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithStrings(), ergolas.WithConcurrency())
+
+	_, err := evaluateIn(ctx, `
+		double := x -> x * 2
+		add := a b -> a + b
+		println (3 |> double |> add 1)
+		println ("a-b-c" |> split "-" |> join "+" _)
+		square-next := (x :: Int) -> { y := x + 1; y * y }
+		println (square-next 2)
+
+		curried := a -> b -> a - b
+		println ((call curried 10) 4)
+
+		results := chan 1
+		results <- "sent"
+		println (<- results)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// 7
+	// a+b+c
+	// 9
+	// 6
+	// sent
+}
@@ -0,0 +1,98 @@
+package ergolas
+
+import "fmt"
+
+// Builtin is a function implemented in Go that also receives the context it
+// gets called from, plain "func(args ...any) (any, error)" values can be
+// called from scripts too.
+type Builtin func(ctx *Context, args ...any) (any, error)
+
+// ContextOption configures a root context, see NewContext
+type ContextOption func(ctx *Context)
+
+// NewContext creates a root context with only the builtins of the packages
+// granted by the given options, e.g. a host running untrusted scripts can
+// leave out WithOS.
+//
+//	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithMath())
+func NewContext(opts ...ContextOption) *Context {
+	ctx := &Context{Bindings: map[string]any{}}
+	for _, opt := range opts {
+		opt(ctx)
+	}
+
+	return ctx
+}
+
+// NewRootContext creates a root context with all the standard packages but
+// the ones touching the host system other than "exit", the given options are
+// applied after them, e.g. to grant WithModules.
+func NewRootContext(opts ...ContextOption) *Context {
+	return NewContext(append([]ContextOption{
+		WithCore(),
+		WithIO(),
+		WithOS(),
+		WithMath(),
+		WithStrings(),
+		WithTime(),
+		WithConcurrency(),
+		WithJSON(),
+		WithRegex(),
+	}, opts...)...)
+}
+
+// WithBindings adds the given bindings to the root context, this can be used
+// by hosts to provide their own builtins.
+func WithBindings(bindings map[string]any) ContextOption {
+	return func(ctx *Context) {
+		for name, value := range bindings {
+			ctx.Bindings[name] = value
+		}
+	}
+}
+
+func expectArgs(args []any, n int) error {
+	if len(args) != n {
+		return fmt.Errorf(`expected %d argument(s), got %d`, n, len(args))
+	}
+
+	return nil
+}
+
+func expectMinArgs(args []any, n int) error {
+	if len(args) < n {
+		return fmt.Errorf(`expected at least %d argument(s), got %d`, n, len(args))
+	}
+
+	return nil
+}
+
+func expectInt(v any) (int64, error) {
+	n, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf(`expected integer but got %v`, TypeOf(v))
+	}
+
+	return n, nil
+}
+
+func expectString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf(`expected string but got %v`, TypeOf(v))
+	}
+
+	return s, nil
+}
+
+// toFloat converts integers and floats to a float
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
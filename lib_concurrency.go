@@ -0,0 +1,246 @@
+package ergolas
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Task is the handle of a function running in its own goroutine, see "spawn"
+type Task struct {
+	done   chan struct{}
+	result any
+	err    error
+}
+
+// Wait blocks until the task ends and returns its result
+func (t *Task) Wait() (any, error) {
+	<-t.done
+	return t.result, t.err
+}
+
+func (t *Task) String() string {
+	return "<task>"
+}
+
+// Channel is a Go channel of script values, see "chan"
+type Channel struct {
+	ch chan any
+}
+
+// NewChannel creates a channel with the given buffer capacity
+func NewChannel(capacity int) *Channel {
+	return &Channel{make(chan any, capacity)}
+}
+
+// Chan returns the underlying Go channel, this can be used by hosts to
+// exchange values with scripts
+func (c *Channel) Chan() chan any {
+	return c.ch
+}
+
+// send sends a value on the channel, it returns early if the evaluation gets
+// cancelled. This is also the operator "c <- v".
+func (c *Channel) send(ctx *Context, v any) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf(`send on closed channel`)
+		}
+	}()
+
+	select {
+	case c.ch <- v:
+		return nil
+	case <-ctx.state.done():
+		return ctx.state.step()
+	}
+}
+
+// recv receives a value from the channel, it returns early if the evaluation
+// gets cancelled. This is also the operator "<- c".
+func (c *Channel) recv(ctx *Context) (any, error) {
+	select {
+	case v := <-c.ch:
+		return v, nil
+	case <-ctx.state.done():
+		return nil, ctx.state.step()
+	}
+}
+
+func (c *Channel) String() string {
+	return fmt.Sprintf("<chan %d/%d>", len(c.ch), cap(c.ch))
+}
+
+// WithConcurrency grants the builtins for running functions concurrently and
+// communicating between them with channels. Tasks share the limits of the
+// evaluation that spawned them and blocking builtins return early when it
+// gets cancelled.
+func WithConcurrency() ContextOption {
+	return WithBindings(map[string]any{
+		// spawn <function> <args>..., calls the function in a new goroutine
+		// and returns a task that can be awaited
+		"spawn": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectMinArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			// the state of the evaluation is captured now as the task can
+			// outlive it
+			caller := ctx.child()
+
+			task := &Task{done: make(chan struct{})}
+			go func() {
+				defer close(task.done)
+				// a panic in the task must not crash the host, it gets
+				// reported by await instead
+				defer func() {
+					if r := recover(); r != nil {
+						task.result, task.err = nil, fmt.Errorf(`internal error in task: %v`, r)
+					}
+				}()
+
+				task.result, task.err = callFunction(caller, args[0], args[1:]...)
+			}()
+
+			return task, nil
+		}),
+		// await <task>, waits for the task to end and returns its result or
+		// its error
+		"await": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			task, ok := args[0].(*Task)
+			if !ok {
+				return nil, fmt.Errorf(`expected task but got %v`, TypeOf(args[0]))
+			}
+
+			select {
+			case <-task.done:
+				return task.result, task.err
+			case <-ctx.state.done():
+				return nil, ctx.state.step()
+			}
+		}),
+		// chan [<capacity>], creates a channel, unbuffered by default
+		"chan": func(args ...any) (any, error) {
+			if len(args) == 0 {
+				return NewChannel(0), nil
+			}
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			capacity, err := expectInt(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if capacity < 0 {
+				return nil, fmt.Errorf(`negative channel capacity %d`, capacity)
+			}
+
+			return NewChannel(int(capacity)), nil
+		},
+		// send <channel> <value>
+		"send": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 2); err != nil {
+				return nil, err
+			}
+
+			c, err := expectChannel(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, c.send(ctx, args[1])
+		}),
+		// recv <channel>, returns nil once the channel is closed and empty
+		"recv": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			c, err := expectChannel(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return c.recv(ctx)
+		}),
+		// close <channel>
+		"close": func(args ...any) (_ any, err error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			c, err := expectChannel(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			defer func() {
+				if recover() != nil {
+					err = fmt.Errorf(`close of closed channel`)
+				}
+			}()
+
+			close(c.ch)
+			return nil, nil
+		},
+		// select <channel> <handler> ... [<default>], waits for a value from
+		// any of the channels and calls its handler with it, with a default
+		// the select doesn't block and calls it if no value is ready
+		"select": Builtin(selectChannels),
+	})
+}
+
+func selectChannels(ctx *Context, args ...any) (any, error) {
+	if err := expectMinArgs(args, 1); err != nil {
+		return nil, err
+	}
+
+	cases := []reflect.SelectCase{}
+	handlers := []any{}
+	for i := 0; i+1 < len(args); i += 2 {
+		c, err := expectChannel(args[i])
+		if err != nil {
+			return nil, err
+		}
+
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.ch)})
+		handlers = append(handlers, args[i+1])
+	}
+
+	hasDefault := len(args)%2 == 1
+	if hasDefault {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	// a nil channel is never ready, so without a cancellable evaluation this
+	// case just never gets selected
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.state.done())})
+
+	chosen, v, ok := reflect.Select(cases)
+	switch {
+	case chosen < len(handlers):
+		var value any
+		if ok {
+			value = v.Interface()
+		}
+
+		return callFunction(ctx, handlers[chosen], value)
+	case hasDefault && chosen == len(handlers):
+		return callIfFunction(ctx, args[len(args)-1])
+	}
+
+	return nil, ctx.state.step()
+}
+
+func expectChannel(v any) (*Channel, error) {
+	c, ok := v.(*Channel)
+	if !ok {
+		return nil, fmt.Errorf(`expected channel but got %v`, TypeOf(v))
+	}
+
+	return c, nil
+}
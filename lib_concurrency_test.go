@@ -0,0 +1,149 @@
+package ergolas_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleWithConcurrency() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithConcurrency())
+
+	_, err := evaluateIn(ctx, `
+		c := call chan
+		task := spawn (fn n { send c (n * 2); n + 1 }) 20
+
+		println (recv c)
+		println (await task)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, _ := evaluateIn(ctx, `select c (fn v { "received" }) { "nothing ready" }`)
+	fmt.Println(result)
+
+	// Output:
+	// 40
+	// 21
+	// nothing ready
+}
+
+func TestSpawnSharedBindings(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithConcurrency())
+
+	result, err := evaluateIn(ctx, `
+		base := 1
+		done := chan 50
+		worker := fn i { x := base + i; send done x }
+		spawn-all := fn n { if (n > 0) { spawn worker n; spawn-all (n - 1) } }
+		spawn-all 50
+
+		base := 2
+		other := "written while the workers run"
+
+		count := fn n acc { if (n == 0) { acc } { recv done; count (n - 1) (acc + 1) } }
+		count 50 0
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != int64(50) {
+		t.Errorf("expected 50 results, got %v", result)
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	in := ergolas.NewInterpreter(ergolas.WithCore())
+	if _, err := in.RunString(`fib := fn n { if (n < 2) n { (fib (n - 1)) + (fib (n - 2)) } }`); err != nil {
+		t.Fatal(err)
+	}
+
+	fib, _ := in.Get("fib")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := ergolas.Call(in.Globals(), fib, 15)
+			if err != nil || result != int64(610) {
+				t.Errorf("expected 610, got %v (%v)", result, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestConcurrencyErrors(t *testing.T) {
+	tests := map[string]string{
+		`await (spawn { missing })`:         `unbound variable "missing"`,
+		`c := call chan; close c; send c 1`: `send on closed channel`,
+		`c := call chan; close c; close c`:  `close of closed channel`,
+		`recv 1`:                            `expected channel but got Int`,
+		`await 1`:                           `expected task but got Int`,
+		`chan (0 - 1)`:                      `negative channel capacity -1`,
+		`c := chan 1; close c; recv c`:      ``,
+		`select (chan 1) (fn v { v }) nil`:  ``,
+		`await (spawn { 1 / 0 })`:           `division by zero`,
+		`await (spawn boom 1)`:              `internal error in task: boom`,
+	}
+
+	for source, expected := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithConcurrency(), ergolas.WithBindings(map[string]any{
+			"boom": func(args ...any) (any, error) { panic("boom") },
+		}))
+
+		_, err := evaluateIn(ctx, source)
+		if expected == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", source, err)
+			}
+		} else if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestConcurrencyCancelled(t *testing.T) {
+	for _, source := range []string{
+		`recv (call chan)`,
+		`send (call chan) 1`,
+		`await (spawn { recv (call chan) })`,
+		`select (call chan) (fn v { v })`,
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+
+		_, err := evaluateIn(ergolas.NewRootContext(), source, ergolas.EvalOptions{Context: ctx})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected deadline exceeded, got %v", source, err)
+		}
+
+		cancel()
+	}
+}
+
+func ExampleChannel() {
+	c := ergolas.NewChannel(1)
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithConcurrency(), ergolas.WithBindings(map[string]any{
+		"events": c,
+	}))
+
+	if _, err := evaluateIn(ctx, `send events "started"`); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(<-c.Chan())
+
+	// Output:
+	// started
+}
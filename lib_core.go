@@ -0,0 +1,112 @@
+package ergolas
+
+import "fmt"
+
+// WithCore grants the basic values and control flow builtins
+func WithCore() ContextOption {
+	return WithBindings(map[string]any{
+		"true":  true,
+		"false": false,
+		"nil":   nil,
+
+		// if <condition> <then> [<else>], the condition and the branches can
+		// be blocks that get called only when needed
+		"if": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if len(args) != 2 && len(args) != 3 {
+				return nil, fmt.Errorf(`expected 2 or 3 arguments, got %d`, len(args))
+			}
+
+			cond, err := callIfFunction(ctx, args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			if isTruthy(cond) {
+				return callIfFunction(ctx, args[1])
+			}
+			if len(args) == 3 {
+				return callIfFunction(ctx, args[2])
+			}
+
+			return nil, nil
+		}),
+		// call <function> <args>...
+		"call": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectMinArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			return callFunction(ctx, args[0], args[1:]...)
+		}),
+		// at <list> <index>, negative indices count from the end
+		// at <map> <key>, the key is a string or a symbol, missing keys give nil.
+		// Map keys are always strings, so a symbol looks up its name and
+		// "at m :k" is the same as "at m "k"".
+		"at": func(args ...any) (any, error) {
+			if err := expectArgs(args, 2); err != nil {
+				return nil, err
+			}
+
+			if m, ok := args[0].(*Map); ok {
+				key, err := expectKey(args[1])
+				if err != nil {
+					return nil, err
+				}
+
+				value, _ := m.Get(key)
+				return value, nil
+			}
+
+			list, ok := args[0].(*List)
+			if !ok {
+				return nil, fmt.Errorf(`expected list or map but got %v`, TypeOf(args[0]))
+			}
+
+			index, err := expectInt(args[1])
+			if err != nil {
+				return nil, err
+			}
+
+			item, ok := list.Get(int(index))
+			if !ok {
+				return nil, fmt.Errorf(`index %d out of range for list of length %d`, index, list.Len())
+			}
+
+			return item, nil
+		},
+		// symbol <string>, returns the symbol with the given name, the name of
+		// a symbol is "s.name". Symbols are never freed so a new one is
+		// charged to the allocation limit.
+		"symbol": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			name, err := expectKey(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			if s, ok := lookupSymbol(name); ok {
+				return s, nil
+			}
+			if err := ctx.state.alloc(symbolAllocSize + int64(len(name))); err != nil {
+				return nil, err
+			}
+
+			return Intern(name), nil
+		}),
+	})
+}
+
+// expectKey accepts a string or the name of a symbol
+func expectKey(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case *Symbol:
+		return v.Name(), nil
+	}
+
+	return "", fmt.Errorf(`expected string or symbol but got %v`, TypeOf(v))
+}
@@ -0,0 +1,141 @@
+package ergolas
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WithStdout sets the writer used by the printing builtins
+func WithStdout(w io.Writer) ContextOption {
+	return func(ctx *Context) {
+		ctx.stdout = w
+	}
+}
+
+// WithStderr sets the writer used by the builtins printing errors
+func WithStderr(w io.Writer) ContextOption {
+	return func(ctx *Context) {
+		ctx.stderr = w
+	}
+}
+
+// WithStdin sets the reader used by the input builtins
+func WithStdin(r io.Reader) ContextOption {
+	return func(ctx *Context) {
+		ctx.stdin = bufio.NewReader(r)
+	}
+}
+
+// WithIO grants the builtins for printing and reading input, they use the
+// streams set with WithStdout, WithStderr and WithStdin.
+func WithIO() ContextOption {
+	return WithBindings(map[string]any{
+		"print": Builtin(func(ctx *Context, args ...any) (any, error) {
+			return nil, printValues(ctx.Stdout(), args, false)
+		}),
+		"println": Builtin(func(ctx *Context, args ...any) (any, error) {
+			return nil, printValues(ctx.Stdout(), args, true)
+		}),
+		"eprint": Builtin(func(ctx *Context, args ...any) (any, error) {
+			return nil, printValues(ctx.Stderr(), args, false)
+		}),
+		"eprintln": Builtin(func(ctx *Context, args ...any) (any, error) {
+			return nil, printValues(ctx.Stderr(), args, true)
+		}),
+		// printf <template> <args>..., see formatTemplate
+		"printf": Builtin(func(ctx *Context, args ...any) (any, error) {
+			return nil, printFormatted(ctx.Stdout(), args, false)
+		}),
+		"printfln": Builtin(func(ctx *Context, args ...any) (any, error) {
+			return nil, printFormatted(ctx.Stdout(), args, true)
+		}),
+		// readline returns the next line of input without the trailing
+		// newline or nil at the end of the input
+		"readline": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 0); err != nil {
+				return nil, err
+			}
+
+			line, err := ctx.Stdin().ReadString('\n')
+			if err == io.EOF && line == "" {
+				return nil, nil
+			}
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+
+			return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+		}),
+	})
+}
+
+func printValues(w io.Writer, args []any, newline bool) error {
+	for _, arg := range args {
+		if _, err := fmt.Fprint(w, arg); err != nil {
+			return err
+		}
+	}
+
+	if newline {
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+
+	return nil
+}
+
+func printFormatted(w io.Writer, args []any, newline bool) error {
+	if err := expectMinArgs(args, 1); err != nil {
+		return err
+	}
+
+	template, err := expectString(args[0])
+	if err != nil {
+		return err
+	}
+
+	s, err := formatTemplate(template, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if newline {
+		s += "\n"
+	}
+
+	_, err = io.WriteString(w, s)
+	return err
+}
+
+// formatTemplate replaces each "{}" in the template with the next argument,
+// "{{" and "}}" are used for literal braces.
+func formatTemplate(template string, args []any) (string, error) {
+	sb := &strings.Builder{}
+	next := 0
+
+	for i := 0; i < len(template); i++ {
+		switch {
+		case strings.HasPrefix(template[i:], "{{"), strings.HasPrefix(template[i:], "}}"):
+			sb.WriteByte(template[i])
+			i++
+		case strings.HasPrefix(template[i:], "{}"):
+			if next >= len(args) {
+				return "", fmt.Errorf(`not enough arguments for template %q`, template)
+			}
+
+			fmt.Fprint(sb, args[next])
+			next++
+			i++
+		default:
+			sb.WriteByte(template[i])
+		}
+	}
+
+	if next < len(args) {
+		return "", fmt.Errorf(`too many arguments for template %q`, template)
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,250 @@
+package ergolas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxJSONIndent is the largest indent accepted as a number of spaces or as
+// the length of a string, larger values would let a script allocate huge
+// strings with a single call
+const maxJSONIndent = 16
+
+// WithJSON grants the "json" map with the builtins
+//
+//	json.parse <string>, objects become maps keeping the order of their keys
+//	and arrays become lists, numbers without a fraction or an exponent that
+//	fit in 64 bits become integers
+//
+//	json.stringify <value> [<indent>], the indent can be a number of spaces
+//	or a string of up to 16 bytes and enables pretty printing
+func WithJSON() ContextOption {
+	namespace := NewMap()
+	namespace.Set("parse", func(args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		s, err := expectString(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return ParseJSON([]byte(s))
+	})
+	namespace.Set("stringify", Builtin(func(ctx *Context, args ...any) (any, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf(`expected 1 or 2 arguments, got %d`, len(args))
+		}
+
+		indent := ""
+		if len(args) == 2 {
+			switch v := args[1].(type) {
+			case int64:
+				if v < 0 {
+					return nil, fmt.Errorf(`negative indent %d`, v)
+				}
+				if v > maxJSONIndent {
+					return nil, fmt.Errorf(`indent %d is larger than %d`, v, maxJSONIndent)
+				}
+
+				indent = strings.Repeat(" ", int(v))
+			case string:
+				if len(v) > maxJSONIndent {
+					return nil, fmt.Errorf(`indent of %d bytes is larger than %d`, len(v), maxJSONIndent)
+				}
+
+				indent = v
+			default:
+				return nil, fmt.Errorf(`expected number or string but got %v`, TypeOf(args[1]))
+			}
+		}
+
+		data, err := StringifyJSON(args[0], indent)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.state.alloc(int64(len(data))); err != nil {
+			return nil, err
+		}
+
+		return string(data), nil
+	}))
+
+	return WithBindings(map[string]any{"json": namespace})
+}
+
+// ParseJSON converts a JSON document to a script value, see WithJSON
+func ParseJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid json: %w`, err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf(`invalid json: unexpected data after the value`)
+	}
+
+	return v, nil
+}
+
+func parseJSONValue(dec *json.Decoder) (any, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			m := NewMap()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := parseJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				m.Set(key.(string), value)
+			}
+
+			_, err := dec.Token()
+			return m, err
+		case '[':
+			items := []any{}
+			for dec.More() {
+				item, err := parseJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				items = append(items, item)
+			}
+
+			_, err := dec.Token()
+			return NewList(items...), err
+		}
+	case json.Number:
+		if n, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return n, nil
+		}
+
+		return strconv.ParseFloat(string(t), 64)
+	case string, bool, nil:
+		return t, nil
+	}
+
+	return nil, fmt.Errorf(`unexpected token %v`, t)
+}
+
+// StringifyJSON converts a script value to JSON, maps keep the order of their
+// keys and floats always have a fraction or an exponent so they are parsed
+// back as floats. A non empty indent enables pretty printing.
+func StringifyJSON(v any, indent string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := writeJSON(buf, v); err != nil {
+		return nil, err
+	}
+
+	if indent == "" {
+		return buf.Bytes(), nil
+	}
+
+	pretty := &bytes.Buffer{}
+	if err := json.Indent(pretty, buf.Bytes(), "", indent); err != nil {
+		return nil, err
+	}
+
+	return pretty.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf(`cannot convert %v to json`, v)
+		}
+
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+
+		buf.WriteString(s)
+	case *Symbol:
+		return writeJSON(buf, v.Name())
+	case time.Time:
+		return writeJSON(buf, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return writeJSON(buf, v.String())
+	case string:
+		// unlike json.Marshal this doesn't escape "<", ">" and "&"
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+
+		buf.Truncate(buf.Len() - 1)
+	case *List:
+		buf.WriteByte('[')
+		for i, item := range v.items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *Map:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, v.values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf(`cannot convert value of type %v to json`, TypeOf(v))
+	}
+
+	return nil
+}
+
+// MarshalJSON makes maps usable with encoding/json, see StringifyJSON
+func (m *Map) MarshalJSON() ([]byte, error) {
+	return StringifyJSON(m, "")
+}
+
+// MarshalJSON makes lists usable with encoding/json, see StringifyJSON
+func (l *List) MarshalJSON() ([]byte, error) {
+	return StringifyJSON(l, "")
+}
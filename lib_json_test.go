@@ -0,0 +1,137 @@
+package ergolas_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleWithJSON() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithJSON())
+
+	_, err := evaluateIn(ctx, `
+		config := json.parse "{\"name\": \"app\", \"port\": 8080, \"ratio\": 1.0, \"tags\": [\"a\", \"b\"]}"
+		println config.name " " (config.port + 1)
+		println (json.stringify config 2)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// app 8081
+	// {
+	//   "name": "app",
+	//   "port": 8080,
+	//   "ratio": 1.0,
+	//   "tags": [
+	//     "a",
+	//     "b"
+	//   ]
+	// }
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"z":1,"a":2,"m":{"y":null,"b":true}}`,
+		`[9007199254740993,-9223372036854775808,1.5,1e+100,1.0]`,
+		`"<tag> & \"quotes\" è"`,
+		`[]`,
+		`{}`,
+	}
+
+	for _, input := range tests {
+		v, err := ergolas.ParseJSON([]byte(input))
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+
+		output, err := ergolas.StringifyJSON(v, "")
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+
+		expected := strings.Replace(input, `è`, "è", 1)
+		if string(output) != expected {
+			t.Errorf("expected %s, got %s", expected, output)
+		}
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	v, err := ergolas.ParseJSON([]byte(`{"big": 9223372036854775807, "huge": 9223372036854775808, "f": 2.5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := v.(*ergolas.Map)
+	for key, expected := range map[string]any{
+		"big":  int64(9223372036854775807),
+		"huge": 9223372036854775808.0,
+		"f":    2.5,
+	} {
+		if actual, _ := m.Get(key); actual != expected {
+			t.Errorf("%s: expected %#v, got %#v", key, expected, actual)
+		}
+	}
+
+	// maps and lists can be used with encoding/json
+	data, err := json.Marshal(map[string]any{"config": m, "list": ergolas.NewList(int64(1), "two")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"config":{"big":9223372036854775807,"huge":9.223372036854776e+18,"f":2.5},"list":[1,"two"]}`; string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithMath(), ergolas.WithJSON())
+	failures := map[string]string{
+		`json.parse "{\"a\": }"`:                   `invalid json: `,
+		`json.parse "[1] [2]"`:                     `invalid json: unexpected data after the value`,
+		`json.stringify math.inf`:                  `cannot convert +Inf to json`,
+		`json.stringify (fn x { x })`:              `cannot convert value of type Fn(Any) -> Any to json`,
+		`json.stringify 1 true`:                    `expected number or string but got Bool`,
+		`json.stringify [1 2] (0 - 1)`:             `negative indent -1`,
+		`json.stringify [1 2] math.max-int`:        `indent 9223372036854775807 is larger than 16`,
+		`json.stringify [1 2] "                 "`: `indent of 17 bytes is larger than 16`,
+	}
+
+	for source, expected := range failures {
+		_, err := evaluateIn(ctx, source)
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%s: expected error starting with %q, got %v", source, expected, err)
+		}
+	}
+
+	if result, err := evaluateIn(ctx, `json.stringify (json.parse "[1, {\"a\": 2}]") "\t"`); err != nil || result != "[\n\t1,\n\t{\n\t\t\"a\": 2\n\t}\n]" {
+		t.Errorf("unexpected pretty output %q (%v)", result, err)
+	}
+}
+
+func TestJSONAllocations(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithJSON(), ergolas.WithBindings(map[string]any{
+		"big": ergolas.NewList(strings.Repeat("x", 1<<16), strings.Repeat("y", 1<<16)),
+	}))
+
+	_, err := evaluateIn(ctx, `json.stringify big`, ergolas.EvalOptions{MaxAllocations: 1 << 16})
+
+	var limitErr ergolas.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.AllocationLimit {
+		t.Fatalf("expected allocations limit error, got %v", err)
+	}
+}
+
+func ExampleParseJSON() {
+	v, _ := ergolas.ParseJSON([]byte(`{"b": [1, 2.5, "x"], "a": null}`))
+	fmt.Println(v)
+
+	// Output:
+	// {b -> [1 2.5 "x"], a -> <nil>}
+}
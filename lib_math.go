@@ -0,0 +1,444 @@
+package ergolas
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithMath grants the math builtins, these are bound both as globals and in
+// the "math" map together with the constants "math.pi", "math.e",
+// "math.inf", "math.max-int" and "math.min-int".
+func WithMath() ContextOption {
+	shared := &lockedRand{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+	builtins := map[string]any{
+		"abs": func(args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			switch n := args[0].(type) {
+			case int64:
+				if n < 0 {
+					return -n, nil
+				}
+				return n, nil
+			case float64:
+				return math.Abs(n), nil
+			}
+
+			return nil, fmt.Errorf(`expected number but got %v`, TypeOf(args[0]))
+		},
+		"min": func(args ...any) (any, error) {
+			return extremum(args, func(a, b float64) bool { return a < b })
+		},
+		"max": func(args ...any) (any, error) {
+			return extremum(args, func(a, b float64) bool { return a > b })
+		},
+		// pow <base> <exponent>, the same as "base ^ exponent"
+		"pow": func(args ...any) (any, error) {
+			if err := expectArgs(args, 2); err != nil {
+				return nil, err
+			}
+
+			return power(args[0], args[1])
+		},
+		"sqrt": floatFunction(math.Sqrt),
+		"exp":  floatFunction(math.Exp),
+		"log":  floatFunction(math.Log),
+		"sin":  floatFunction(math.Sin),
+		"cos":  floatFunction(math.Cos),
+		"tan":  floatFunction(math.Tan),
+		"asin": floatFunction(math.Asin),
+		"acos": floatFunction(math.Acos),
+		"atan": floatFunction(math.Atan),
+		"atan2": func(args ...any) (any, error) {
+			if err := expectArgs(args, 2); err != nil {
+				return nil, err
+			}
+
+			y, err := expectNumber(args[0])
+			if err != nil {
+				return nil, err
+			}
+			x, err := expectNumber(args[1])
+			if err != nil {
+				return nil, err
+			}
+
+			return math.Atan2(y, x), nil
+		},
+		"floor": roundingFunction(math.Floor),
+		"ceil":  roundingFunction(math.Ceil),
+		// round <number> [<digits>], without digits rounds to the nearest
+		// integer, otherwise to a float with the given number of decimal digits
+		"round": func(args ...any) (any, error) {
+			if len(args) != 2 {
+				return roundingFunction(math.Round)(args...)
+			}
+
+			x, err := expectNumber(args[0])
+			if err != nil {
+				return nil, err
+			}
+			digits, err := expectInt(args[1])
+			if err != nil {
+				return nil, err
+			}
+
+			scale := math.Pow(10, float64(digits))
+			return math.Round(x*scale) / scale, nil
+		},
+		// random, a float between 0 and 1 excluded
+		// random <n>, an integer from 0 to n excluded
+		// random <from> <to>, an integer from "from" to "to" included
+		"random": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if len(args) > 2 {
+				return nil, fmt.Errorf(`expected at most 2 arguments, got %d`, len(args))
+			}
+
+			rng := seededRand(ctx, shared)
+			if len(args) == 0 {
+				return rng.float(), nil
+			}
+
+			bounds := []int64{}
+			for _, arg := range args {
+				n, err := expectInt(arg)
+				if err != nil {
+					return nil, err
+				}
+
+				bounds = append(bounds, n)
+			}
+
+			// the range is from "from" to "to" both included
+			from, to := int64(0), bounds[0]-1
+			if len(bounds) == 2 {
+				from, to = bounds[0], bounds[1]
+			} else if bounds[0] <= 0 {
+				return nil, fmt.Errorf(`empty range for random`)
+			}
+			if to < from {
+				return nil, fmt.Errorf(`empty range for random`)
+			}
+
+			// the size of the range doesn't fit an int64 when it is larger
+			// than math.max-int, it wraps to 0 for the full range
+			size := uint64(to) - uint64(from) + 1
+			return int64(uint64(from) + rng.uint64n(size)), nil
+		}),
+		// seed <n>, makes the following random numbers of the same evaluation
+		// or fork deterministic, other scripts using the same context keep
+		// their own sequence
+		"seed": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			n, err := expectInt(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			owner := rngOwner(ctx)
+			owner.mu.Lock()
+			owner.rng = &lockedRand{rand: rand.New(rand.NewSource(n))}
+			owner.mu.Unlock()
+
+			return nil, nil
+		}),
+		// int <value>, converts floats truncating them and parses strings
+		"int": func(args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			switch v := args[0].(type) {
+			case int64:
+				return v, nil
+			case float64:
+				return floatToInt(v)
+			case string:
+				n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf(`invalid integer %q`, v)
+				}
+
+				return n, nil
+			}
+
+			return nil, fmt.Errorf(`cannot convert %v to Int`, TypeOf(args[0]))
+		},
+		// float <value>, converts integers and parses strings
+		"float": func(args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			switch v := args[0].(type) {
+			case int64:
+				return float64(v), nil
+			case float64:
+				return v, nil
+			case string:
+				x, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return nil, fmt.Errorf(`invalid float %q`, v)
+				}
+
+				return x, nil
+			}
+
+			return nil, fmt.Errorf(`cannot convert %v to Float`, TypeOf(args[0]))
+		},
+		// str <value> [<digits>], formats a value as a string, numbers can be
+		// formatted with a fixed number of decimal digits
+		"str": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, fmt.Errorf(`expected 1 or 2 arguments, got %d`, len(args))
+			}
+			if len(args) == 1 {
+				s := fmt.Sprint(args[0])
+				if err := ctx.state.alloc(int64(len(s))); err != nil {
+					return nil, err
+				}
+
+				return s, nil
+			}
+
+			x, err := expectNumber(args[0])
+			if err != nil {
+				return nil, err
+			}
+			digits, err := expectInt(args[1])
+			if err != nil {
+				return nil, err
+			}
+			if digits < 0 {
+				return nil, fmt.Errorf(`negative number of digits %d`, digits)
+			}
+
+			s := strconv.FormatFloat(x, 'f', int(digits), 64)
+			if err := ctx.state.alloc(int64(len(s))); err != nil {
+				return nil, err
+			}
+
+			return s, nil
+		}),
+	}
+
+	namespace := NewMap()
+	namespace.Set("pi", math.Pi)
+	namespace.Set("e", math.E)
+	namespace.Set("inf", math.Inf(+1))
+	namespace.Set("max-int", int64(math.MaxInt64))
+	namespace.Set("min-int", int64(math.MinInt64))
+
+	names := []string{}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		namespace.Set(name, builtins[name])
+	}
+
+	builtins["math"] = namespace
+	return WithBindings(builtins)
+}
+
+// lockedRand is a random source safe for concurrent use
+type lockedRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (r *lockedRand) float() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rand.Float64()
+}
+
+// uint64n returns a number from 0 to n excluded, n equal to 0 stands for the
+// full range of uint64
+func (r *lockedRand) uint64n(n uint64) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n == 0 {
+		return r.rand.Uint64()
+	}
+	if n <= math.MaxInt64 {
+		return uint64(r.rand.Int63n(int64(n)))
+	}
+
+	// n is larger than half the range so at most half of the draws get
+	// rejected
+	for {
+		if v := r.rand.Uint64(); v < n {
+			return v
+		}
+	}
+}
+
+// rngOwner returns the context where "seed" stores its random source, that
+// is the context of the evaluation, of the fork or the root one
+func rngOwner(ctx *Context) *Context {
+	cur := ctx
+	for !cur.forward && cur.Parent != nil && !cur.Parent.frozen.Load() {
+		cur = cur.Parent
+	}
+
+	return cur
+}
+
+// seededRand returns the random source seeded in this context or its parents,
+// or the shared one if "seed" was never called
+func seededRand(ctx *Context, shared *lockedRand) *lockedRand {
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		cur.mu.RLock()
+		rng := cur.rng
+		cur.mu.RUnlock()
+
+		if rng != nil {
+			return rng
+		}
+	}
+
+	return shared
+}
+
+// power computes "base ^ exponent", integers with a non negative integer
+// exponent give an integer and everything else a float. An integer result
+// that doesn't fit an Int is an error.
+func power(base, exponent any) (any, error) {
+	if b, ok := base.(int64); ok {
+		if e, ok := exponent.(int64); ok && e >= 0 {
+			result, square := int64(1), b
+			for n := e; n > 0; n >>= 1 {
+				var ok bool
+				if n&1 == 1 {
+					if result, ok = multiplyInts(result, square); !ok {
+						return nil, fmt.Errorf(`integer overflow in %d ^ %d`, b, e)
+					}
+				}
+				if n > 1 {
+					if square, ok = multiplyInts(square, square); !ok {
+						return nil, fmt.Errorf(`integer overflow in %d ^ %d`, b, e)
+					}
+				}
+			}
+
+			return result, nil
+		}
+	}
+
+	b, ok := toFloat(base)
+	e, ok2 := toFloat(exponent)
+	if !ok || !ok2 {
+		return nil, fmt.Errorf(`cannot apply operator "^" to types %T and %T`, base, exponent)
+	}
+
+	return math.Pow(b, e), nil
+}
+
+// multiplyInts returns the product of two integers and whether it didn't
+// overflow
+func multiplyInts(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	return c, true
+}
+
+// floatToInt truncates a float to an integer
+func floatToInt(x float64) (int64, error) {
+	if math.IsNaN(x) || x >= math.MaxInt64 || x < math.MinInt64 {
+		return 0, fmt.Errorf(`cannot convert %v to Int`, x)
+	}
+
+	return int64(x), nil
+}
+
+func expectNumber(v any) (float64, error) {
+	x, ok := toFloat(v)
+	if !ok {
+		return 0, fmt.Errorf(`expected number but got %v`, TypeOf(v))
+	}
+
+	return x, nil
+}
+
+// floatFunction wraps a Go function from floats to floats as a builtin,
+// integer arguments are converted to floats
+func floatFunction(f func(float64) float64) func(args ...any) (any, error) {
+	return func(args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		x, err := expectNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return f(x), nil
+	}
+}
+
+// roundingFunction wraps a Go rounding function as a builtin returning an
+// integer, integer arguments are returned unchanged
+func roundingFunction(f func(float64) float64) func(args ...any) (any, error) {
+	return func(args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return floatToInt(f(v))
+		}
+
+		return nil, fmt.Errorf(`expected number but got %v`, TypeOf(args[0]))
+	}
+}
+
+// extremum returns the argument that is better than all others, the result is
+// the original value so integers stay integers
+func extremum(args []any, better func(a, b float64) bool) (any, error) {
+	if err := expectMinArgs(args, 1); err != nil {
+		return nil, err
+	}
+
+	var best any
+	var bestValue float64
+	for i, arg := range args {
+		n, ok := toFloat(arg)
+		if !ok {
+			return nil, fmt.Errorf(`expected number but got %v`, TypeOf(arg))
+		}
+
+		if i == 0 || better(n, bestValue) {
+			best, bestValue = arg, n
+		}
+	}
+
+	return best, nil
+}
@@ -0,0 +1,232 @@
+package ergolas
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModuleExtension is added to imported paths without an extension
+const ModuleExtension = ".erg"
+
+// moduleLoader loads and caches the modules imported from a root context
+type moduleLoader struct {
+	fsys fs.FS
+	root *Context
+
+	mu      sync.Mutex
+	modules map[string]*module
+}
+
+// module is a module loaded or being loaded, done gets closed once its
+// evaluation ends
+type module struct {
+	path    string
+	done    chan struct{}
+	exports *Map
+	err     error
+
+	// waiting is the module whose evaluation this one is blocked on, it is
+	// used to find cycles between imports from different tasks and is
+	// guarded by the mutex of the loader
+	waiting *module
+}
+
+// WithModules grants the "import" builtin resolving paths in the given file
+// system, e.g. "os.DirFS(dir)" or an "fstest.MapFS" for tests.
+//
+// The builtin "import <path>" evaluates the file in a new scope of the root
+// context and returns its bindings as a map, so they can be used as in
+//
+//	utils := import "lib/utils"
+//	utils.helper 42
+//
+// Paths starting with "./" or "../" are relative to the importing module,
+// other paths to the root of the file system. Each module is evaluated only
+// once and later imports return the same map. Import cycles are errors, also
+// when the modules of the cycle are imported by different tasks.
+func WithModules(fsys fs.FS) ContextOption {
+	return func(ctx *Context) {
+		ctx.modules = &moduleLoader{fsys: fsys, root: ctx, modules: map[string]*module{}}
+		ctx.Bindings["import"] = Builtin(importModule)
+	}
+}
+
+func importModule(ctx *Context, args ...any) (any, error) {
+	if err := expectArgs(args, 1); err != nil {
+		return nil, err
+	}
+
+	name, err := expectString(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var loader *moduleLoader
+	var imports []string
+	for cur := ctx; cur != nil; cur = cur.Parent {
+		if imports == nil {
+			imports = cur.imports
+		}
+		if cur.modules != nil {
+			loader = cur.modules
+			break
+		}
+	}
+	if loader == nil {
+		return nil, fmt.Errorf(`modules are not available`)
+	}
+
+	current := ""
+	if len(imports) > 0 {
+		current = imports[len(imports)-1]
+	}
+
+	return loader.load(ctx, imports, resolveModulePath(current, name))
+}
+
+// resolveModulePath returns the path in the file system of the module
+// imported with the given name from the module at path "from"
+func resolveModulePath(from, name string) string {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		name = path.Join(path.Dir(from), name)
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if path.Ext(name) == "" {
+		name += ModuleExtension
+	}
+
+	return name
+}
+
+// load returns the exports of a module, evaluating it if this is the first
+// time it gets imported, otherwise waiting for its evaluation to end
+func (l *moduleLoader) load(caller *Context, imports []string, modulePath string) (*Map, error) {
+	for i, loading := range imports {
+		if loading == modulePath {
+			cycle := append(append([]string{}, imports[i:]...), modulePath)
+			return nil, fmt.Errorf(`import cycle: %s`, strings.Join(cycle, " -> "))
+		}
+	}
+
+	l.mu.Lock()
+	m, loaded := l.modules[modulePath]
+	if !loaded {
+		m = &module{path: modulePath, done: make(chan struct{})}
+		l.modules[modulePath] = m
+	}
+
+	// the importing module is blocked until this one is loaded
+	var importing *module
+	if len(imports) > 0 {
+		importing = l.modules[imports[len(imports)-1]]
+	}
+	if loaded && importing != nil {
+		if cycle := l.waitCycle(imports, m); cycle != nil {
+			l.mu.Unlock()
+			return nil, fmt.Errorf(`import cycle: %s`, strings.Join(cycle, " -> "))
+		}
+	}
+	if importing != nil {
+		importing.waiting = m
+		defer func() {
+			l.mu.Lock()
+			importing.waiting = nil
+			l.mu.Unlock()
+		}()
+	}
+	l.mu.Unlock()
+
+	if loaded {
+		select {
+		case <-m.done:
+			return m.exports, m.err
+		case <-caller.state.done():
+			return nil, caller.state.step()
+		}
+	}
+
+	m.exports, m.err = l.evaluate(caller, append(append([]string{}, imports...), modulePath))
+	if m.err != nil {
+		// failed modules are not cached so they can be imported again
+		l.mu.Lock()
+		delete(l.modules, modulePath)
+		l.mu.Unlock()
+	}
+	close(m.done)
+
+	return m.exports, m.err
+}
+
+// waitCycle returns the cycle of imports closed by waiting on the module m
+// from the given import chain, i.e. when m is being loaded by another task
+// that is in turn blocked on a module of the chain, or nil if there is none.
+// It must be called with the mutex of the loader held.
+func (l *moduleLoader) waitCycle(imports []string, m *module) []string {
+	blocked := []string{}
+	for cur := m; cur != nil; cur = cur.waiting {
+		for i, loading := range imports {
+			if loading == cur.path {
+				return append(append(append([]string{}, imports[i:]...), blocked...), cur.path)
+			}
+		}
+
+		// a cycle not involving the chain isn't this import's to report
+		for _, path := range blocked {
+			if path == cur.path {
+				return nil
+			}
+		}
+
+		blocked = append(blocked, cur.path)
+	}
+
+	return nil
+}
+
+// evaluate evaluates the last module of the import chain in a new scope of
+// the root context and returns its bindings
+func (l *moduleLoader) evaluate(caller *Context, imports []string) (*Map, error) {
+	modulePath := imports[len(imports)-1]
+
+	source, err := fs.ReadFile(l.fsys, modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := Tokenize(string(source))
+	if err != nil {
+		return nil, fmt.Errorf(`%s: %w`, modulePath, err)
+	}
+
+	node, err := Parse(tokens)
+	if err != nil {
+		return nil, fmt.Errorf(`%s: %w`, modulePath, err)
+	}
+
+	// the module is evaluated with the limits of the importing script
+	scope := &Context{Parent: l.root, Bindings: map[string]any{}, state: caller.state, imports: imports}
+	if _, err := eval(node, scope); err != nil {
+		return nil, fmt.Errorf(`%s: %w`, modulePath, err)
+	}
+
+	scope.mu.RLock()
+	defer scope.mu.RUnlock()
+
+	names := []string{}
+	for name := range scope.Bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	exports := NewMap()
+	for _, name := range names {
+		exports.Set(name, scope.Bindings[name])
+	}
+
+	return exports, nil
+}
@@ -0,0 +1,113 @@
+package ergolas_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleWithModules() {
+	fsys := fstest.MapFS{
+		"lib/utils.erg": {Data: []byte(`
+			shout := fn s { (upper s) + "!" }
+			greeting := "hello"
+		`)},
+	}
+
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithStrings(), ergolas.WithModules(fsys))
+
+	result, err := evaluateIn(ctx, `
+		utils := import "lib/utils"
+		utils.shout utils.greeting
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(result)
+
+	// Output:
+	// HELLO!
+}
+
+func TestModulesRelativeAndCached(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.erg":        {Data: []byte(`a := import "./lib/a"; b := import "lib/b"`)},
+		"lib/a.erg":       {Data: []byte(`counter := import "./counter"`)},
+		"lib/b.erg":       {Data: []byte(`counter := import "../lib/counter.erg"`)},
+		"lib/counter.erg": {Data: []byte(`println "loaded"; value := 1`)},
+	}
+
+	var out strings.Builder
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithStdout(&out), ergolas.WithModules(fsys))
+
+	result, err := evaluateIn(ctx, `
+		main := import "main"
+		main.a.counter == main.b.counter
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != true {
+		t.Errorf("expected the same module for both imports")
+	}
+	if out.String() != "loaded\n" {
+		t.Errorf("expected the module to be evaluated once, got output %q", out.String())
+	}
+}
+
+func TestModulesErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.erg":   {Data: []byte(`b := import "b"`)},
+		"b.erg":   {Data: []byte(`a := import "a"`)},
+		"bad.erg": {Data: []byte(`x := missing`)},
+		"ok.erg":  {Data: []byte(`x := 1`)},
+	}
+
+	tests := map[string]string{
+		`import "a"`:        `import cycle: a.erg -> b.erg -> a.erg`,
+		`import "bad"`:      `bad.erg: unbound variable "missing"`,
+		`import "nope"`:     `file does not exist`,
+		`(import "ok").nah`: `no property "nah" in map`,
+		`1.foo`:             `value of type Int has no property "foo"`,
+	}
+
+	for source, expected := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithModules(fsys))
+
+		_, err := evaluateIn(ctx, source)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestModulesCycleBetweenTasks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.erg": {Data: []byte(`sleep 20ms; b := import "b"`)},
+		"b.erg": {Data: []byte(`sleep 20ms; a := import "a"`)},
+	}
+
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithTime(), ergolas.WithConcurrency(), ergolas.WithModules(fsys))
+
+	// without detecting the cycle each task would wait for the other until
+	// the deadline
+	deadline, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := evaluateIn(ctx, `
+		ta := spawn { import "a" }
+		tb := spawn { import "b" }
+		[(await ta) (await tb)]
+	`, ergolas.EvalOptions{Context: deadline})
+	if err == nil || !strings.Contains(err.Error(), `import cycle: `) {
+		t.Fatalf("expected an import cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package ergolas
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithOS grants the builtins interacting with the host process
+func WithOS() ContextOption {
+	return WithBindings(map[string]any{
+		// exit doesn't terminate the process but stops the evaluation with an
+		// ExitError, the host decides what to do with it.
+		"exit": func(args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			nExitCode, err := expectInt(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, ExitError{int(nExitCode)}
+		},
+	})
+}
+
+// WithFS grants the "fs" map with builtins for reading and writing files, all
+// paths are resolved inside the given root directory and ".." can't go above
+// it. Symbolic links inside the root are followed so they should not point
+// outside of it.
+//
+//	fs.read <path>, returns the content of a file as a string
+//	fs.write <path> <content>, creates or truncates a file
+//	fs.list <path>, returns the sorted list of the names in a directory
+//	fs.exists <path>
+func WithFS(root string) ContextOption {
+	namespace := NewMap()
+	namespace.Set("read", Builtin(func(ctx *Context, args ...any) (any, error) {
+		name, err := expectPath(root, args, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, relativePathError(root, err)
+		}
+		if err := ctx.state.alloc(int64(len(data))); err != nil {
+			return nil, err
+		}
+
+		return string(data), nil
+	}))
+	namespace.Set("write", func(args ...any) (any, error) {
+		name, err := expectPath(root, args, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := expectString(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, relativePathError(root, os.WriteFile(name, []byte(content), 0o644))
+	})
+	namespace.Set("list", func(args ...any) (any, error) {
+		name, err := expectPath(root, args, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := os.ReadDir(name)
+		if err != nil {
+			return nil, relativePathError(root, err)
+		}
+
+		names := []any{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+
+		return NewList(names...), nil
+	})
+	namespace.Set("exists", func(args ...any) (any, error) {
+		name, err := expectPath(root, args, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = os.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return nil, relativePathError(root, err)
+		}
+
+		return true, nil
+	})
+
+	return WithBindings(map[string]any{"fs": namespace})
+}
+
+// WithEnv grants the "env" map with the builtin "env.get <name>" returning
+// the value of an environment variable or nil, the variables are read with
+// the given lookup function so hosts can expose only some of them.
+//
+//	ergolas.WithEnv(os.LookupEnv)
+func WithEnv(lookup func(name string) (string, bool)) ContextOption {
+	namespace := NewMap()
+	namespace.Set("get", func(args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		name, err := expectString(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if value, ok := lookup(name); ok {
+			return value, nil
+		}
+
+		return nil, nil
+	})
+
+	return WithBindings(map[string]any{"env": namespace})
+}
+
+// WithExec grants the builtin "exec <command> <args>..." running a process in
+// the given directory. It returns a map with the captured "stdout" and
+// "stderr" and the exit "code", a process exiting with an error is not an
+// error of the script. The process gets killed if the evaluation is
+// cancelled.
+//
+// Scripts can run any binary on the PATH with any arguments, so the process
+// has all the permissions of the host and is not confined to the directory
+// given here nor to the root of WithFS.
+func WithExec(dir string) ContextOption {
+	return WithBindings(map[string]any{
+		"exec": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectMinArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			argv := []string{}
+			for _, arg := range args {
+				s, err := expectString(arg)
+				if err != nil {
+					return nil, err
+				}
+
+				argv = append(argv, s)
+			}
+
+			var stdout, stderr bytes.Buffer
+
+			cmd := exec.CommandContext(ctx.state.context(), argv[0], argv[1:]...)
+			cmd.Dir = dir
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			code := 0
+			if err := cmd.Run(); err != nil {
+				var exitErr *exec.ExitError
+				if !errors.As(err, &exitErr) {
+					return nil, err
+				}
+				// a process killed because the evaluation got cancelled
+				// reports the cancellation instead of its exit code, like
+				// the blocking builtins of WithConcurrency do
+				if err := ctx.state.step(); err != nil {
+					return nil, err
+				}
+
+				code = exitErr.ExitCode()
+			}
+
+			if err := ctx.state.alloc(int64(stdout.Len() + stderr.Len())); err != nil {
+				return nil, err
+			}
+
+			result := NewMap()
+			result.Set("stdout", stdout.String())
+			result.Set("stderr", stderr.String())
+			result.Set("code", int64(code))
+			return result, nil
+		}),
+	})
+}
+
+// expectPath checks the number of arguments and resolves the first one as a
+// path inside the root directory. Leading ".." in paths using "/" stop at the
+// root, paths that still end up outside of it (e.g. "..\x" on Windows) are
+// rejected.
+func expectPath(root string, args []any, n int) (string, error) {
+	if err := expectArgs(args, n); err != nil {
+		return "", err
+	}
+
+	name, err := expectString(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	full := filepath.Clean(filepath.Join(root, filepath.FromSlash(path.Clean("/"+name))))
+
+	rel, err := filepath.Rel(root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf(`path %q is outside of the root`, name)
+	}
+
+	return full, nil
+}
+
+// relativePathError removes the root directory from the paths in errors so
+// scripts don't see where they are sandboxed
+func relativePathError(root string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		rel, relErr := filepath.Rel(root, pathErr.Path)
+		if relErr != nil {
+			rel = pathErr.Path
+		}
+
+		return fmt.Errorf(`%s %s: %w`, pathErr.Op, filepath.ToSlash(rel), pathErr.Err)
+	}
+
+	return err
+}
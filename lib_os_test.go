@@ -0,0 +1,137 @@
+package ergolas_test
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleWithFS() {
+	root, err := os.MkdirTemp("", "ergolas")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithFS(root))
+
+	_, err = evaluateIn(ctx, `
+		fs.write "notes.txt" "hello"
+		println (fs.read "/notes.txt")
+		println (fs.exists "notes.txt") " " (fs.exists "missing.txt")
+		println (fs.list ".")
+		println (fs.read "../../notes.txt")
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// hello
+	// true false
+	// ["notes.txt"]
+	// hello
+}
+
+func TestFSRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`fs.read "../secret.txt"`, `open secret.txt: no such file or directory`},
+		{`fs.read "/../../secret.txt"`, `open secret.txt: no such file or directory`},
+		{`fs.list "missing"`, `open missing: no such file or directory`},
+		{`fs.write "../secret.txt" 1`, `expected string but got Int`},
+	}
+
+	if runtime.GOOS == "windows" {
+		tests = append(tests, struct {
+			source string
+			err    string
+		}{`fs.read "..\\secret.txt"`, `path "..\\secret.txt" is outside of the root`})
+	}
+
+	for _, test := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithFS(root))
+		_, err := evaluateIn(ctx, test.source)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: expected error %q, got %v", test.source, test.err, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "secret.txt")); err == nil {
+		t.Errorf("expected no file written in the root")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "HOME" {
+			return "/home/user", true
+		}
+
+		return "", false
+	}
+
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithEnv(lookup))
+
+	tests := map[string]any{
+		`env.get "HOME"`: "/home/user",
+		`env.get "PATH"`: nil,
+	}
+
+	for source, expected := range tests {
+		result, err := evaluateIn(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", source, expected, result)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithExec(dir))
+
+	result, err := evaluateIn(ctx, `exec "sh" "-c" "pwd; echo oops >&2; exit 3"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := result.(*ergolas.Map)
+	stdout, _ := m.Get("stdout")
+	stderr, _ := m.Get("stderr")
+	code, _ := m.Get("code")
+
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if strings.TrimSpace(stdout.(string)) != dir || stderr != "oops\n" || code != int64(3) {
+		t.Errorf("unexpected result %v", m)
+	}
+
+	if _, err := evaluateIn(ctx, `exec "ergolas-missing-command"`); err == nil {
+		t.Errorf("expected an error for a missing command")
+	}
+}
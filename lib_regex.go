@@ -0,0 +1,243 @@
+package ergolas
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// regexBuiltins are the builtins of WithRegex, all of them take a regex or a
+// pattern string as first argument so they are also available as methods of
+// regex values, e.g. "re.match s" is the same as "regex.match re s".
+var regexBuiltins map[string]Builtin
+
+// the map is filled in init as "replace" calls back into the evaluator, which
+// refers to the map through regexMethod
+func init() {
+	regexBuiltins = map[string]Builtin{
+		// match <regex> <string>, returns nil if the regex doesn't match or a map
+		// with the matched "text", its "start" and "end" character offsets, the
+		// list of the capture "groups" and the "named" groups by name
+		"match": func(ctx *Context, args ...any) (any, error) {
+			re, s, err := expectRegexAndString(args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			loc := re.FindStringSubmatchIndex(s)
+			if loc == nil {
+				return nil, nil
+			}
+
+			return regexMatch(re, s, loc), nil
+		},
+		// find-all <regex> <string>, returns the list of all the matched strings or
+		// the list of the matches like "match" when the regex has capture groups
+		"find-all": func(ctx *Context, args ...any) (any, error) {
+			re, s, err := expectRegexAndString(args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			items := []any{}
+			for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+				if re.NumSubexp() == 0 {
+					items = append(items, s[loc[0]:loc[1]])
+				} else {
+					items = append(items, regexMatch(re, s, loc))
+				}
+			}
+
+			return NewList(items...), nil
+		},
+		// replace <regex> <string> <replacement>, replaces all the matches. The
+		// replacement is either a string where "$1" or "${name}" stand for the
+		// groups or a function called with each match like "match" and returning
+		// the string to put in its place.
+		"replace": func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 3); err != nil {
+				return nil, err
+			}
+
+			re, s, err := expectRegexAndString(args[:2], 2)
+			if err != nil {
+				return nil, err
+			}
+
+			var result string
+			switch replacement := args[2].(type) {
+			case string:
+				result = re.ReplaceAllString(s, replacement)
+			case *Function, func(args ...any) (any, error), Builtin:
+				sb := &strings.Builder{}
+				last := 0
+				for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+					v, err := callFunction(ctx, replacement, regexMatch(re, s, loc))
+					if err != nil {
+						return nil, err
+					}
+
+					part, err := expectString(v)
+					if err != nil {
+						return nil, err
+					}
+
+					sb.WriteString(s[last:loc[0]])
+					sb.WriteString(part)
+					last = loc[1]
+				}
+				sb.WriteString(s[last:])
+
+				result = sb.String()
+			default:
+				return nil, fmt.Errorf(`expected string or function but got %v`, TypeOf(args[2]))
+			}
+
+			if err := ctx.state.alloc(int64(len(result))); err != nil {
+				return nil, err
+			}
+
+			return result, nil
+		},
+		// split <regex> <string>, returns the list of the parts between the matches
+		"split": func(ctx *Context, args ...any) (any, error) {
+			re, s, err := expectRegexAndString(args, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			items := []any{}
+			for _, part := range re.Split(s, -1) {
+				items = append(items, part)
+			}
+
+			return NewList(items...), nil
+		},
+	}
+}
+
+// WithRegex grants the "regex" map with the regular expression builtins of
+// regexBuiltins and "regex.compile <pattern>" and "regex.quote <string>".
+// Regex literals are written as r"..." where only \" is an escape, all other
+// backslashes are part of the pattern, e.g. r"(?P<year>\d{4})-(?P<month>\d{2})".
+func WithRegex() ContextOption {
+	namespace := NewMap()
+	namespace.Set("compile", func(args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		return expectRegex(args[0])
+	})
+	namespace.Set("quote", stringFunction(regexp.QuoteMeta))
+
+	names := []string{}
+	for name := range regexBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		namespace.Set(name, regexBuiltins[name])
+	}
+
+	return WithBindings(map[string]any{"regex": namespace})
+}
+
+// regexMethod returns the method with the given name of a regex
+func regexMethod(re *regexp.Regexp, name string) (any, error) {
+	if name == "pattern" {
+		return re.String(), nil
+	}
+
+	builtin, ok := regexBuiltins[name]
+	if !ok {
+		return nil, fmt.Errorf(`value of type %v has no property "%s"`, RegexType, name)
+	}
+
+	return Builtin(func(ctx *Context, args ...any) (any, error) {
+		return builtin(ctx, append([]any{re}, args...)...)
+	}), nil
+}
+
+// regexMatch converts the submatch indices of a match to the map returned by
+// "match", offsets are in characters like the indices of "slice"
+func regexMatch(re *regexp.Regexp, s string, loc []int) *Map {
+	group := func(i int) any {
+		if loc[2*i] < 0 {
+			return nil
+		}
+
+		return s[loc[2*i]:loc[2*i+1]]
+	}
+
+	groups := []any{}
+	named := NewMap()
+	for i, name := range re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+
+		groups = append(groups, group(i))
+		if name != "" {
+			named.Set(name, group(i))
+		}
+	}
+
+	m := NewMap()
+	m.Set("text", group(0))
+	m.Set("start", int64(utf8.RuneCountInString(s[:loc[0]])))
+	m.Set("end", int64(utf8.RuneCountInString(s[:loc[1]])))
+	m.Set("groups", NewList(groups...))
+	m.Set("named", named)
+	return m
+}
+
+// compileRegex compiles the pattern of a regex literal or of "regex.compile"
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf(`invalid regex: %s: %s`, syntaxErr.Code, syntaxErr.Expr)
+		}
+
+		return nil, err
+	}
+
+	return re, nil
+}
+
+// expectRegex accepts a regex or a pattern string to compile
+func expectRegex(v any) (*regexp.Regexp, error) {
+	switch v := v.(type) {
+	case *regexp.Regexp:
+		return v, nil
+	case string:
+		return compileRegex(v)
+	}
+
+	return nil, fmt.Errorf(`expected regex but got %v`, TypeOf(v))
+}
+
+func expectRegexAndString(args []any, n int) (*regexp.Regexp, string, error) {
+	if err := expectArgs(args, n); err != nil {
+		return nil, "", err
+	}
+
+	re, err := expectRegex(args[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	s, err := expectString(args[1])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return re, s, nil
+}
@@ -0,0 +1,81 @@
+package ergolas_test
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleWithRegex() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithStrings(), ergolas.WithRegex())
+
+	_, err := evaluateIn(ctx, `
+		date := r"(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})"
+		m := date.match "released on 2024-05-31"
+		println m.text " at " m.start " " m.named
+		println (r"\d+".find-all "1 22 333")
+		println (r"\w+".replace "hello world" (fn m { m.text.upper }))
+		println (date.replace "2024-05-31" "${day}/${month}/$year")
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// 2024-05-31 at 12 {year -> 2024, month -> 05, day -> 31}
+	// ["1" "22" "333"]
+	// HELLO WORLD
+	// 31/05/2024
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`r"a\"b".pattern`, `a"b`},
+		{`r"x".match "abc"`, `<nil>`},
+		{`(r"(a)(z)?".match "èa").groups`, `["a" <nil>]`},
+		{`(r"(a)(z)?".match "èa").end`, `2`},
+		{`regex.find-all r"(\w)=(\d)" "a=1 b=2"`, `[{text -> a=1, start -> 0, end -> 3, groups -> ["a" "1"], named -> {}} {text -> b=2, start -> 4, end -> 7, groups -> ["b" "2"], named -> {}}]`},
+		{`regex.replace "\\s+" "a  b   c" " "`, `a b c`},
+		{`r",\s*".split "a, b,c"`, `["a" "b" "c"]`},
+		{`regex.match (regex.quote "1+1") "1+1=2"`, `{text -> 1+1, start -> 0, end -> 3, groups -> [], named -> {}}`},
+	}
+
+	for _, test := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithStrings(), ergolas.WithRegex())
+		result, err := evaluateIn(ctx, test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+
+		if s := fmt.Sprint(result); s != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.source, test.expected, s)
+		}
+	}
+}
+
+func TestRegexErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`r"(a"`, `invalid regex: missing closing ): (a`},
+		{`regex.compile "[a"`, `invalid regex: missing closing ]: [a`},
+		{`r"a".replace "aaa" (fn m { 1 })`, `expected string but got Int`},
+		{`r"a".replace "aaa" 1`, `expected string or function but got Int`},
+		{`r"a".flags`, `value of type Regex has no property "flags"`},
+	}
+
+	for _, test := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithRegex())
+		_, err := evaluateIn(ctx, test.source)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: expected error %q, got %v", test.source, test.err, err)
+		}
+	}
+}
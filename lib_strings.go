@@ -0,0 +1,259 @@
+package ergolas
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringBuiltins are the builtins of WithStrings, all of them take a string
+// as first argument so they are also available as methods of strings, e.g.
+// "s.split" is the same as "split s".
+var stringBuiltins = map[string]Builtin{
+	// len <value>, the number of characters of a string or the number of
+	// items of a list or a map
+	"len": func(ctx *Context, args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		switch v := args[0].(type) {
+		case string:
+			return int64(utf8.RuneCountInString(v)), nil
+		case *List:
+			return int64(v.Len()), nil
+		case *Map:
+			return int64(v.Len()), nil
+		}
+
+		return nil, fmt.Errorf(`expected string, list or map but got %v`, TypeOf(args[0]))
+	},
+	"upper": stringFunction(strings.ToUpper),
+	"lower": stringFunction(strings.ToLower),
+	"trim":  stringFunction(strings.TrimSpace),
+	// split <string> <separator>, returns the list of the parts
+	"split": func(ctx *Context, args ...any) (any, error) {
+		s, strs, err := expectStrings(args, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		items := []any{}
+		for _, part := range strings.Split(s, strs[0]) {
+			items = append(items, part)
+		}
+
+		return NewList(items...), nil
+	},
+	// join <separator> <list>, the items of the list must be strings
+	"join": func(ctx *Context, args ...any) (any, error) {
+		if err := expectArgs(args, 2); err != nil {
+			return nil, err
+		}
+
+		sep, err := expectString(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		list, ok := args[1].(*List)
+		if !ok {
+			return nil, fmt.Errorf(`expected list but got %v`, TypeOf(args[1]))
+		}
+
+		parts := []string{}
+		for _, item := range list.items {
+			part, err := expectString(item)
+			if err != nil {
+				return nil, err
+			}
+
+			parts = append(parts, part)
+		}
+
+		result := strings.Join(parts, sep)
+		if err := ctx.state.alloc(int64(len(result))); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	},
+	// replace <string> <old> <new>, replaces all the occurrences
+	"replace": func(ctx *Context, args ...any) (any, error) {
+		s, strs, err := expectStrings(args, 3)
+		if err != nil {
+			return nil, err
+		}
+
+		result := strings.ReplaceAll(s, strs[0], strs[1])
+		if err := ctx.state.alloc(int64(len(result))); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	},
+	"contains":    stringPredicate(strings.Contains),
+	"starts-with": stringPredicate(strings.HasPrefix),
+	"ends-with":   stringPredicate(strings.HasSuffix),
+	// format <template> <args>..., replaces each "{}" with the next argument
+	// like printf
+	"format": func(ctx *Context, args ...any) (any, error) {
+		if err := expectMinArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		template, err := expectString(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := formatTemplate(template, args[1:])
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.state.alloc(int64(len(result))); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	},
+	// slice <value> <start> [<end>], the characters of a string or the items
+	// of a list from start to end excluded, negative indices count from the end
+	"slice": func(ctx *Context, args ...any) (any, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf(`expected 2 or 3 arguments, got %d`, len(args))
+		}
+
+		var length int
+		switch v := args[0].(type) {
+		case string:
+			length = utf8.RuneCountInString(v)
+		case *List:
+			length = v.Len()
+		default:
+			return nil, fmt.Errorf(`expected string or list but got %v`, TypeOf(args[0]))
+		}
+
+		start, err := expectInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		end := int64(length)
+		if len(args) == 3 {
+			if end, err = expectInt(args[2]); err != nil {
+				return nil, err
+			}
+		}
+
+		from, to := sliceIndex(start, length), sliceIndex(end, length)
+		if from > to {
+			to = from
+		}
+
+		if list, ok := args[0].(*List); ok {
+			return NewList(list.items[from:to]...), nil
+		}
+
+		return string([]rune(args[0].(string))[from:to]), nil
+	},
+}
+
+// eagerStringMethods are the methods of strings without other arguments, these
+// get called by the property access itself as in "s.upper"
+var eagerStringMethods = map[string]bool{
+	"len":   true,
+	"upper": true,
+	"lower": true,
+	"trim":  true,
+}
+
+// WithStrings grants the string manipulation builtins
+func WithStrings() ContextOption {
+	bindings := map[string]any{}
+	for name, builtin := range stringBuiltins {
+		bindings[name] = builtin
+	}
+
+	return WithBindings(bindings)
+}
+
+// stringMethod returns the method with the given name of a string
+func stringMethod(ctx *Context, s string, name string) (any, error) {
+	builtin, ok := stringBuiltins[name]
+	if !ok {
+		return nil, fmt.Errorf(`value of type %v has no property "%s"`, StringType, name)
+	}
+
+	if eagerStringMethods[name] {
+		return builtin(ctx, s)
+	}
+
+	return Builtin(func(ctx *Context, args ...any) (any, error) {
+		return builtin(ctx, append([]any{s}, args...)...)
+	}), nil
+}
+
+// sliceIndex clamps an index to the range from 0 to n, negative indices count
+// from the end
+func sliceIndex(i int64, n int) int {
+	if i < 0 {
+		i += int64(n)
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > int64(n) {
+		return n
+	}
+
+	return int(i)
+}
+
+// expectStrings checks that there are n arguments all strings and returns the
+// first one and the others
+func expectStrings(args []any, n int) (string, []string, error) {
+	if err := expectArgs(args, n); err != nil {
+		return "", nil, err
+	}
+
+	strs := []string{}
+	for _, arg := range args {
+		s, err := expectString(arg)
+		if err != nil {
+			return "", nil, err
+		}
+
+		strs = append(strs, s)
+	}
+
+	return strs[0], strs[1:], nil
+}
+
+// stringFunction wraps a Go function from strings to strings as a builtin
+func stringFunction(f func(string) string) Builtin {
+	return func(ctx *Context, args ...any) (any, error) {
+		if err := expectArgs(args, 1); err != nil {
+			return nil, err
+		}
+
+		s, err := expectString(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return f(s), nil
+	}
+}
+
+// stringPredicate wraps a Go function testing two strings as a builtin
+func stringPredicate(f func(s, t string) bool) Builtin {
+	return func(ctx *Context, args ...any) (any, error) {
+		s, strs, err := expectStrings(args, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		return f(s, strs[0]), nil
+	}
+}
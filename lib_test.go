@@ -0,0 +1,394 @@
+package ergolas_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func parseSource(source string) (ergolas.Node, error) {
+	tokens, err := ergolas.Tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return ergolas.ParseExpressions(tokens)
+}
+
+// evaluateIn evaluates the source in the given context, when options are
+// passed the evaluation is limited like in EvaluateWithOptions
+func evaluateIn(ctx *ergolas.Context, source string, opts ...ergolas.EvalOptions) (any, error) {
+	node, err := parseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts) > 0 {
+		return ergolas.EvaluateWithOptions(node, ctx, opts[0])
+	}
+
+	return ergolas.EvaluateWith(node, ctx)
+}
+
+func ExampleNewContext() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithMath())
+
+	result, err := evaluateIn(ctx, `if { (max 1 5 3) > 4 } { "big" } { "small" }`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(result)
+
+	_, err = evaluateIn(ctx, `exit 1`)
+	fmt.Println(err)
+
+	// Output:
+	// big
+	// unbound variable "exit"
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		option   ergolas.ContextOption
+		builtins []string
+	}{
+		{ergolas.WithCore(), []string{"true", "false", "nil", "if", "call", "at", "symbol"}},
+		{ergolas.WithIO(), []string{"print", "println", "eprint", "eprintln", "printf", "printfln", "readline"}},
+		{ergolas.WithOS(), []string{"exit"}},
+		{ergolas.WithMath(), []string{
+			"abs", "min", "max", "pow", "sqrt", "exp", "log", "sin", "cos", "tan", "asin", "acos", "atan", "atan2",
+			"floor", "ceil", "round", "random", "seed", "int", "float", "str", "math",
+		}},
+		{ergolas.WithStrings(), []string{"len", "upper", "lower", "trim", "split", "join", "replace", "contains", "starts-with", "ends-with", "format", "slice"}},
+		{ergolas.WithTime(), []string{"now", "sleep", "parse-time", "format-time"}},
+		{ergolas.WithConcurrency(), []string{"spawn", "await", "chan", "send", "recv", "close", "select"}},
+		{ergolas.WithJSON(), []string{"json"}},
+		{ergolas.WithRegex(), []string{"regex"}},
+		{ergolas.WithFS("."), []string{"fs"}},
+		{ergolas.WithEnv(os.LookupEnv), []string{"env"}},
+		{ergolas.WithExec("."), []string{"exec"}},
+	}
+
+	for _, test := range tests {
+		names := ergolas.NewContext(test.option).Names()
+		if strings.Join(names, " ") != strings.Join(sortedCopy(test.builtins), " ") {
+			t.Errorf("expected builtins %v, got %v", test.builtins, names)
+		}
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithCore())
+
+	tests := map[string]any{
+		`1 == 1.0`:            true,
+		`"a" != "b"`:          true,
+		`2 < 1`:               false,
+		`"abc" <= "abd"`:      true,
+		`3.5 >= 3`:            true,
+		`if (1 > 2) 1 2`:      int64(2),
+		`call (fn x { x }) 7`: int64(7),
+	}
+
+	for source, expected := range tests {
+		result, err := evaluateIn(ctx, source)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", source, expected, result)
+		}
+	}
+}
+
+func ExampleWithStrings() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithStrings())
+
+	_, err := evaluateIn(ctx, `
+		words := split "  Hello, wörld  ".trim ", "
+		println words
+		println (", ".join (slice words 0 1)) " " (len (at words 1))
+		println ("ergolas".slice 0 (0 - 2)).upper
+		println ("{} + {} = {}".format 1 2 3)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// ["Hello" "wörld"]
+	// Hello 5
+	// ERGOL
+	// 1 + 2 = 3
+}
+
+func TestStrings(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithStrings())
+
+	tests := map[string]any{
+		`len "città"`:                       int64(5),
+		`"a\tb\n".len`:                      int64(4),
+		`"\u00e8\"" == "è\""`:               true,
+		`replace "a-b-c" "-" "+"`:           "a+b+c",
+		`"a-b-c".replace "-" ""`:            "abc",
+		`contains "ergolas" "gola"`:         true,
+		`"ergolas".starts-with "erg"`:       true,
+		`"ergolas".ends-with "erg"`:         false,
+		`"héllo".slice 1 3`:                 "él",
+		`"héllo".slice (0 - 3)`:             "llo",
+		`"héllo".slice 4 2`:                 "",
+		`slice "abc" 1 100`:                 "bc",
+		`len (slice (split "a b c" " ") 1)`: int64(2),
+		`join "" (split "a b c" " ")`:       "abc",
+		`format "{{{}}}" "x"`:               "{x}",
+		`lower "ABC"`:                       "abc",
+		`"multi` + "\n" + `line".len`:       int64(10),
+	}
+
+	for source, expected := range tests {
+		result, err := evaluateIn(ctx, source)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", source, expected, result)
+		}
+	}
+
+	failures := map[string]string{
+		`"abc".nope`:              `value of type String has no property "nope"`,
+		`split "abc" 1`:           `expected string but got Int`,
+		`join "," (split "a" "")`: ``,
+		`"abc".slice`:             ``,
+		`format "{} {}" 1`:        `not enough arguments for template "{} {}"`,
+		`"\q"`:                    `invalid escape sequence in string "\q"`,
+	}
+
+	for source, expected := range failures {
+		_, err := evaluateIn(ctx, source)
+		if expected == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", source, err)
+			}
+		} else if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestStringAllocations(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithStrings(), ergolas.WithBindings(map[string]any{
+		"big": strings.Repeat("x", 1<<16),
+	}))
+
+	for _, source := range []string{
+		`replace big "x" "xxxx"`,
+		`join big (split "a b c" " ")`,
+		`format "{}{}{}{}" big big big big`,
+	} {
+		_, err := evaluateIn(ctx, source, ergolas.EvalOptions{MaxAllocations: 1 << 17})
+
+		var limitErr ergolas.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.AllocationLimit {
+			t.Errorf("%s: expected allocations limit error, got %v", source, err)
+		}
+	}
+}
+
+func TestMath(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithMath())
+
+	tests := map[string]any{
+		`2 ^ 10`:                   int64(1024),
+		`2 ^ 62`:                   int64(1 << 62),
+		`(0 - 2) ^ 63`:             int64(math.MinInt64),
+		`(0 - 1) ^ math.max-int`:   int64(-1),
+		`2 ^ (0 - 1)`:              0.5,
+		`4 ^ 0.5`:                  2.0,
+		`pow 3 3`:                  int64(27),
+		`sqrt 16`:                  4.0,
+		`math.sqrt 2.25`:           1.5,
+		`floor 2.7`:                int64(2),
+		`ceil 2.1`:                 int64(3),
+		`round 2.5`:                int64(3),
+		`round 7`:                  int64(7),
+		`round 3.14159 2`:          3.14,
+		`abs (0 - 3)`:              int64(3),
+		`min 3 1.5 2`:              1.5,
+		`int "42"`:                 int64(42),
+		`int (0.0 - 2.9)`:          int64(-2),
+		`float 2`:                  2.0,
+		`float " 1.5 "`:            1.5,
+		`str 3.14159 2`:            "3.14",
+		`str 10 1`:                 "10.0",
+		`str 42`:                   "42",
+		`str 0.5`:                  "0.5",
+		`(sin 0) == 0`:             true,
+		`(cos math.pi) == (0 - 1)`: true,
+		`(atan2 1 1) * 4.0`:        math.Pi,
+		`math.max-int + 0`:         int64(math.MaxInt64),
+		`math.inf > 1000000.0`:     true,
+		`(random 1) == 0`:          true,
+		`(random 5 5) == 5`:        true,
+		`(call random) < 1.0`:      true,
+		`(random math.max-int math.max-int) == math.max-int`: true,
+		`(random math.min-int math.min-int) == math.min-int`: true,
+		`(random math.min-int 0) <= 0`:                       true,
+		`(random 1 math.max-int) >= 1`:                       true,
+		`(random (0 - 1) math.max-int) >= (0 - 1)`:           true,
+		`(random math.min-int math.max-int) <= math.max-int`: true,
+	}
+
+	for source, expected := range tests {
+		result, err := evaluateIn(ctx, source)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", source, expected, result)
+		}
+	}
+
+	failures := map[string]string{
+		`int "abc"`:       `invalid integer "abc"`,
+		`floor math.inf`:  `cannot convert +Inf to Int`,
+		`random 0`:        `empty range for random`,
+		`random (0 - 3)`:  `empty range for random`,
+		`random 2 1`:      `empty range for random`,
+		`"a" ^ 2`:         `cannot apply operator "^" to types string and int64`,
+		`2 ^ 63`:          `integer overflow in 2 ^ 63`,
+		`3 ^ 100`:         `integer overflow in 3 ^ 100`,
+		`sqrt "4"`:        `expected number but got String`,
+		`str 1.5 (0 - 1)`: `negative number of digits -1`,
+	}
+
+	for source, expected := range failures {
+		_, err := evaluateIn(ctx, source)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	sequence := func() any {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithMath(), ergolas.WithStrings())
+
+		result, err := evaluateIn(ctx, `
+			seed 42
+			format "{} {} {}" (random 100) (random 1 6) (str (call random) 4)
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return result
+	}
+
+	if first, second := sequence(), sequence(); first != second {
+		t.Errorf("expected the same sequence with the same seed, got %v and %v", first, second)
+	}
+}
+
+func TestRandomSeedConcurrent(t *testing.T) {
+	root := ergolas.NewContext(ergolas.WithCore(), ergolas.WithMath(), ergolas.WithStrings())
+	program, err := parseSource(`seed 42; format "{} {} {}" (random 100) (random 100) (random 100)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ergolas.EvaluateWith(program, root.Fork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// scripts seeding the same shared context concurrently each get their
+	// own sequence, unaffected by scripts using the unseeded one
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				result, err := ergolas.EvaluateWith(program, root.Fork())
+				if err != nil || result != expected {
+					t.Errorf("expected %v, got %v (%v)", expected, result, err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				if _, err := evaluateIn(root.Fork(), `random 100`); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMathAllocations(t *testing.T) {
+	ctx := ergolas.NewContext(ergolas.WithMath(), ergolas.WithBindings(map[string]any{
+		"big": ergolas.NewList(strings.Repeat("x", 1<<16)),
+	}))
+
+	_, err := evaluateIn(ctx, `str big`, ergolas.EvalOptions{MaxAllocations: 1 << 16})
+
+	var limitErr ergolas.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.AllocationLimit {
+		t.Fatalf("expected allocations limit error, got %v", err)
+	}
+}
+
+func TestRedirectedIO(t *testing.T) {
+	stdout, stderr := &strings.Builder{}, &strings.Builder{}
+	ctx := ergolas.NewContext(
+		ergolas.WithCore(),
+		ergolas.WithIO(),
+		ergolas.WithStdout(stdout),
+		ergolas.WithStderr(stderr),
+		ergolas.WithStdin(strings.NewReader("Alice\nBob")),
+	)
+
+	_, err := evaluateIn(ctx, `
+		first := call readline
+		second := call readline
+		printfln "hello {} and {}{{}}" first second
+		print "no newline " 42
+		eprintln "oops"
+		println (call readline)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "hello Alice and Bob{}\nno newline 42<nil>\n"; stdout.String() != expected {
+		t.Errorf("expected stdout %q, got %q", expected, stdout.String())
+	}
+	if expected := "oops\n"; stderr.String() != expected {
+		t.Errorf("expected stderr %q, got %q", expected, stderr.String())
+	}
+}
+
+func sortedCopy(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,265 @@
+package ergolas
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// timeLayouts are the layouts tried by "parse-time" when none is given
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// WithTime grants the builtins for reading the clock, waiting and working
+// with times and durations, durations are written as literals like "500ms" or
+// "1h30m".
+//
+// Times and durations support the operators "+" and "-" between them, a
+// duration can be multiplied or divided by a number and all of them can be
+// compared. The properties "year", "month", "day", "hour", "minute",
+// "second", "weekday" and "unix" give the parts of a time and "hours",
+// "minutes", "seconds" and "ms" the length of a duration.
+func WithTime() ContextOption {
+	return WithBindings(map[string]any{
+		"now": func(args ...any) (any, error) {
+			if err := expectArgs(args, 0); err != nil {
+				return nil, err
+			}
+
+			// without the monotonic clock reading times print and compare
+			// like the parsed ones
+			return time.Now().Round(0), nil
+		},
+		// sleep <duration>, the duration can also be a number of milliseconds,
+		// returns early with an error if the evaluation gets cancelled
+		"sleep": Builtin(func(ctx *Context, args ...any) (any, error) {
+			if err := expectArgs(args, 1); err != nil {
+				return nil, err
+			}
+
+			d, ok := args[0].(time.Duration)
+			if !ok {
+				ms, err := expectInt(args[0])
+				if err != nil {
+					return nil, fmt.Errorf(`expected duration or number but got %v`, TypeOf(args[0]))
+				}
+
+				d = time.Duration(ms) * time.Millisecond
+			}
+
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+				return nil, nil
+			case <-ctx.state.done():
+				return nil, ctx.state.step()
+			}
+		}),
+		// parse-time <string> [<layout>], the layout uses the reference time
+		// of Go "2006-01-02 15:04:05", without a layout RFC 3339 and the
+		// layouts "2006-01-02 15:04:05", "2006-01-02 15:04" and "2006-01-02"
+		// are tried. Times without a zone are in UTC.
+		"parse-time": func(args ...any) (any, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, fmt.Errorf(`expected 1 or 2 arguments, got %d`, len(args))
+			}
+
+			s, err := expectString(args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			layouts := timeLayouts
+			if len(args) == 2 {
+				layout, err := expectString(args[1])
+				if err != nil {
+					return nil, err
+				}
+
+				layouts = []string{layout}
+			}
+
+			for _, layout := range layouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t, nil
+				}
+			}
+
+			return nil, fmt.Errorf(`invalid time %q`, s)
+		},
+		// format-time <time> [<layout>], formats a time as RFC 3339 or with
+		// the given layout like "parse-time"
+		"format-time": func(args ...any) (any, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, fmt.Errorf(`expected 1 or 2 arguments, got %d`, len(args))
+			}
+
+			t, ok := args[0].(time.Time)
+			if !ok {
+				return nil, fmt.Errorf(`expected time but got %v`, TypeOf(args[0]))
+			}
+
+			layout := time.RFC3339
+			if len(args) == 2 {
+				var err error
+				if layout, err = expectString(args[1]); err != nil {
+					return nil, err
+				}
+			}
+
+			return t.Format(layout), nil
+		},
+	})
+}
+
+// timeOperation evaluates the arithmetic operators on times and durations, ok
+// is false for other operators or when none of the operands is a time or a
+// duration
+func timeOperation(op string, a, b any) (result any, ok bool, err error) {
+	ta, aIsTime := a.(time.Time)
+	tb, bIsTime := b.(time.Time)
+	da, aIsDuration := a.(time.Duration)
+	db, bIsDuration := b.(time.Duration)
+
+	switch {
+	case !aIsTime && !bIsTime && !aIsDuration && !bIsDuration:
+		return nil, false, nil
+	case op != "+" && op != "-" && op != "*" && op != "/" && op != "%":
+		return nil, false, nil
+	}
+
+	switch {
+	case op == "+" && aIsTime && bIsDuration:
+		return ta.Add(db), true, nil
+	case op == "+" && aIsDuration && bIsTime:
+		return tb.Add(da), true, nil
+	case op == "-" && aIsTime && bIsDuration:
+		return ta.Add(-db), true, nil
+	case op == "-" && aIsTime && bIsTime:
+		return ta.Sub(tb), true, nil
+	case op == "+" && aIsDuration && bIsDuration:
+		return da + db, true, nil
+	case op == "-" && aIsDuration && bIsDuration:
+		return da - db, true, nil
+	case op == "%" && aIsDuration && bIsDuration && db != 0:
+		return da % db, true, nil
+	case op == "/" && aIsDuration && bIsDuration && db != 0:
+		return float64(da) / float64(db), true, nil
+	case op == "*" && aIsDuration:
+		if n, ok := toFloat(b); ok {
+			return scaleDuration(da, n), true, nil
+		}
+	case op == "*" && bIsDuration:
+		if n, ok := toFloat(a); ok {
+			return scaleDuration(db, n), true, nil
+		}
+	case op == "/" && aIsDuration:
+		if n, ok := toFloat(b); ok && n != 0 {
+			return scaleDuration(da, 1/n), true, nil
+		}
+	}
+
+	if (op == "/" || op == "%") && (bIsDuration && db == 0 || isEqual(b, int64(0))) {
+		return nil, true, fmt.Errorf(`division by zero`)
+	}
+
+	return nil, true, fmt.Errorf(`cannot apply operator "%s" to types %v and %v`, op, TypeOf(a), TypeOf(b))
+}
+
+// scaleDuration multiplies a duration by a number rounding to nanoseconds
+func scaleDuration(d time.Duration, n float64) time.Duration {
+	return time.Duration(math.Round(float64(d) * n))
+}
+
+// timeOperationType is the static counterpart of timeOperation
+func timeOperationType(op string, a, b Type) (Type, bool) {
+	isNumber := func(t Type) bool { return t == IntType || t == FloatType }
+
+	switch {
+	case op == "+" && (a == TimeType && b == DurationType || a == DurationType && b == TimeType):
+		return TimeType, true
+	case op == "-" && a == TimeType && b == DurationType:
+		return TimeType, true
+	case op == "-" && a == TimeType && b == TimeType:
+		return DurationType, true
+	case (op == "+" || op == "-" || op == "%") && a == DurationType && b == DurationType:
+		return DurationType, true
+	case op == "/" && a == DurationType && b == DurationType:
+		return FloatType, true
+	case op == "*" && (a == DurationType && isNumber(b) || isNumber(a) && b == DurationType):
+		return DurationType, true
+	case op == "/" && a == DurationType && isNumber(b):
+		return DurationType, true
+	}
+
+	return nil, false
+}
+
+// compareTime compares two times or two durations
+func compareTime(a, b any) (int, bool) {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	if da, ok := a.(time.Duration); ok {
+		if db, ok := b.(time.Duration); ok {
+			switch {
+			case da < db:
+				return -1, true
+			case da > db:
+				return +1, true
+			}
+
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
+
+// timeProperty evaluates the property access "t.name" on a time
+func timeProperty(t time.Time, name string) (any, error) {
+	switch name {
+	case "year":
+		return int64(t.Year()), nil
+	case "month":
+		return int64(t.Month()), nil
+	case "day":
+		return int64(t.Day()), nil
+	case "hour":
+		return int64(t.Hour()), nil
+	case "minute":
+		return int64(t.Minute()), nil
+	case "second":
+		return int64(t.Second()), nil
+	case "weekday":
+		return t.Weekday().String(), nil
+	case "unix":
+		return t.Unix(), nil
+	}
+
+	return nil, fmt.Errorf(`value of type %v has no property "%s"`, TimeType, name)
+}
+
+// durationProperty evaluates the property access "d.name" on a duration
+func durationProperty(d time.Duration, name string) (any, error) {
+	switch name {
+	case "hours":
+		return d.Hours(), nil
+	case "minutes":
+		return d.Minutes(), nil
+	case "seconds":
+		return d.Seconds(), nil
+	case "ms":
+		return d.Milliseconds(), nil
+	}
+
+	return nil, fmt.Errorf(`value of type %v has no property "%s"`, DurationType, name)
+}
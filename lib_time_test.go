@@ -0,0 +1,115 @@
+package ergolas_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleWithTime() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithTime())
+
+	_, err := evaluateIn(ctx, `
+		start := parse-time "2024-05-31 22:30"
+		end := start + 1h30m
+		println (format-time end "Mon 2 Jan 15:04") " " end.weekday
+		println (end - start) " " (end - start).minutes
+		println (end > start) " " (2 * 45m == 1h30m)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// Sat 1 Jun 00:00 Saturday
+	// 1h30m0s 90
+	// true true
+}
+
+func TestTimeOperators(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`500ms + 1.5s`, `2s`},
+		{`1h30m - 30m`, `1h0m0s`},
+		{`1h / 4`, `15m0s`},
+		{`1h / 4.0`, `15m0s`},
+		{`1h / 30m`, `2`},
+		{`90s % 1m`, `30s`},
+		{`1.5 * 10ms`, `15ms`},
+		{`10ms < 1s`, `true`},
+		{`1m == 60s`, `true`},
+		{`(parse-time "2024-01-01") - 1s`, `2023-12-31 23:59:59 +0000 UTC`},
+		{`(parse-time "2024-01-02") - (parse-time "2024-01-01")`, `24h0m0s`},
+		{`(parse-time "2024-01-01T01:00:00+01:00") == (parse-time "2024-01-01")`, `true`},
+		{`(parse-time "01/02/2024" "01/02/2006").month`, `1`},
+	}
+
+	for _, test := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithTime())
+		result, err := evaluateIn(ctx, test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+
+		if s := fmt.Sprint(result); s != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.source, test.expected, s)
+		}
+	}
+}
+
+func TestTimeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`1s + 1`, `cannot apply operator "+" to types Duration and Int`},
+		{`(parse-time "2024-01-01") + (parse-time "2024-01-01")`, `cannot apply operator "+" to types Time and Time`},
+		{`1s / 0`, `division by zero`},
+		{`1s < 1`, `cannot apply operator "<" to types time.Duration and int64`},
+		{`parse-time "yesterday"`, `invalid time "yesterday"`},
+		{`1s.days`, `value of type Duration has no property "days"`},
+	}
+
+	for _, test := range tests {
+		ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithTime())
+		_, err := evaluateIn(ctx, test.source)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: expected error %q, got %v", test.source, test.err, err)
+		}
+	}
+}
+
+func TestTokenizeDurations(t *testing.T) {
+	tests := map[string]string{
+		`500ms`:     `Duration:500ms`,
+		`1h30m`:     `Duration:1h30m`,
+		`1.5h + 2s`: `Duration:1.5h LOperator:+ Duration:2s`,
+		`(10s)`:     `Punctuation:( Duration:10s Punctuation:)`,
+		`10seconds`: `Integer:10 Identifier:seconds`,
+		`5hours`:    `Integer:5 Identifier:hours`,
+		`3msg`:      `Integer:3 Identifier:msg`,
+		`1h30`:      `Integer:1 Identifier:h30`,
+	}
+
+	for source, expected := range tests {
+		tokens, err := ergolas.Tokenize(source)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+
+		parts := []string{}
+		for _, token := range tokens {
+			parts = append(parts, fmt.Sprintf("%s:%s", token.Type, token.Value))
+		}
+		if actual := strings.Join(parts, " "); actual != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ergolas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// List is an immutable sequence of values
+type List struct {
+	items []any
+}
+
+// NewList creates a list with the given items
+func NewList(items ...any) *List {
+	return &List{items}
+}
+
+// Len returns the number of items in the list
+func (l *List) Len() int {
+	return len(l.items)
+}
+
+// Get returns the item at the given index, negative indices count from the
+// end of the list
+func (l *List) Get(index int) (any, bool) {
+	if index < 0 {
+		index += len(l.items)
+	}
+	if index < 0 || index >= len(l.items) {
+		return nil, false
+	}
+
+	return l.items[index], true
+}
+
+// Items returns a copy of the items of the list
+func (l *List) Items() []any {
+	return append([]any{}, l.items...)
+}
+
+func (l *List) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprint(sb, "[")
+	for i, item := range l.items {
+		if i > 0 {
+			fmt.Fprint(sb, " ")
+		}
+		if s, ok := item.(string); ok {
+			fmt.Fprintf(sb, "%q", s)
+		} else {
+			fmt.Fprint(sb, item)
+		}
+	}
+	fmt.Fprint(sb, "]")
+	return sb.String()
+}
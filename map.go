@@ -0,0 +1,84 @@
+package ergolas
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Map is a mapping from names to values that keeps the order in which keys
+// were first set, its entries can be read with property access "m.key".
+type Map struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewMap creates an empty map
+func NewMap() *Map {
+	return &Map{values: map[string]any{}}
+}
+
+// Get returns the value of a key and whether it is present
+func (m *Map) Get(key string) (any, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Set sets the value of a key, new keys are added at the end
+func (m *Map) Set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+
+	m.values[key] = value
+}
+
+// Keys returns the keys of the map in insertion order
+func (m *Map) Keys() []string {
+	return append([]string{}, m.keys...)
+}
+
+// Len returns the number of entries in the map
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+func (m *Map) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprint(sb, "{")
+	for i, key := range m.keys {
+		if i > 0 {
+			fmt.Fprint(sb, ", ")
+		}
+		fmt.Fprintf(sb, "%s -> %v", key, m.values[key])
+	}
+	fmt.Fprint(sb, "}")
+	return sb.String()
+}
+
+// propertyOf evaluates the property access "v.name"
+func propertyOf(ctx *Context, v any, name string) (any, error) {
+	switch v := v.(type) {
+	case *Map:
+		if value, ok := v.Get(name); ok {
+			return value, nil
+		}
+
+		return nil, fmt.Errorf(`no property "%s" in map`, name)
+	case string:
+		return stringMethod(ctx, v, name)
+	case time.Time:
+		return timeProperty(v, name)
+	case time.Duration:
+		return durationProperty(v, name)
+	case *regexp.Regexp:
+		return regexMethod(v, name)
+	case *Symbol:
+		if name == "name" {
+			return v.Name(), nil
+		}
+	}
+
+	return nil, fmt.Errorf(`value of type %v has no property "%s"`, TypeOf(v), name)
+}
@@ -0,0 +1,340 @@
+package ergolas
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// matchArm is a single case "<Pattern> (if <Guard>)? -> <Body>" of a match
+type matchArm struct {
+	Pattern Node
+	Guard   Node
+	Body    Node
+}
+
+// matchExpression matches "match <Expression> { <MatchArm>* }" and returns
+// the matched expression and the arms of the match
+func matchExpression(node Node) (subject Node, arms []matchArm, ok bool) {
+	children := node.Children()
+	if node.Type() != FunctionCallNode || len(children) != 3 || !isIdentifier(children[0], "match") {
+		return nil, nil, false
+	}
+	if children[2].Type() != BlockNode {
+		return nil, nil, false
+	}
+
+	for _, stmt := range children[2].Children() {
+		arm, ok := matchCase(stmt)
+		if !ok {
+			return nil, nil, false
+		}
+
+		arms = append(arms, arm)
+	}
+
+	return children[1], arms, true
+}
+
+// matchCase matches a single arm of a match, the guard is everything after
+// the "if" so "x if even x -> ..." doesn't need parentheses
+func matchCase(node Node) (matchArm, bool) {
+	if node.Type() != BinaryExpressionNode || node.Children()[1].Metadata()["Value"] != "->" {
+		return matchArm{}, false
+	}
+
+	lhs, body := node.Children()[0], node.Children()[2]
+	if lhs.Type() != FunctionCallNode {
+		return matchArm{Pattern: lhs, Body: body}, true
+	}
+
+	children := lhs.Children()
+	if len(children) < 3 || !isIdentifier(children[1], "if") {
+		return matchArm{}, false
+	}
+
+	guard := children[2]
+	if len(children) > 3 {
+		guard = listNode{FunctionCallNode, children[2:], Span{children[2].Span().Start, lhs.Span().End}}
+	}
+
+	return matchArm{Pattern: children[0], Guard: guard, Body: body}, true
+}
+
+// isDestructuring tells whether the left side of a declaration is a pattern
+// like "[a b]" or "{x y}" instead of a single name
+func isDestructuring(node Node) bool {
+	return node.Type() == ListNode || node.Type() == BlockNode
+}
+
+// patternLiterals are the identifiers that in a pattern stand for their value
+// instead of binding a new variable
+var patternLiterals = map[string]any{
+	"true":  true,
+	"false": false,
+	"nil":   nil,
+}
+
+// patternBindings returns the identifiers bound by a pattern in order
+func patternBindings(pattern Node) []Node {
+	switch pattern.Type() {
+	case IdentifierNode:
+		name := pattern.Metadata()["Value"].(string)
+		if _, ok := patternLiterals[name]; ok || name == "_" {
+			return nil
+		}
+
+		return []Node{pattern}
+
+	case ParenthesisNode:
+		if name, _, ok := typeAnnotation(pattern.Children()[0]); ok {
+			return patternBindings(name)
+		}
+
+		return patternBindings(pattern.Children()[0])
+
+	case ListNode:
+		bindings := []Node{}
+		for _, item := range pattern.Children() {
+			bindings = append(bindings, patternBindings(item)...)
+		}
+
+		return bindings
+
+	case BlockNode:
+		fields, _ := recordFields(pattern)
+		return patternBindings(listNode{ListNode, fields, pattern.Span()})
+	}
+
+	return nil
+}
+
+// recordFields returns the names in a record pattern like "{x y}" or
+// "{x; y}", these are the keys the matched map must have
+func recordFields(pattern Node) ([]Node, error) {
+	fields := []Node{}
+	for _, stmt := range pattern.Children() {
+		names := []Node{stmt}
+		if stmt.Type() == FunctionCallNode {
+			names = stmt.Children()
+		}
+
+		for _, name := range names {
+			if name.Type() != IdentifierNode {
+				return nil, fmt.Errorf(`expected field name in record pattern`)
+			}
+
+			fields = append(fields, name)
+		}
+	}
+
+	return fields, nil
+}
+
+// patternMismatch is the reason a value doesn't match a pattern, unlike the
+// other errors of matchPattern it just means the match failed
+type patternMismatch struct {
+	reason string
+}
+
+func (e patternMismatch) Error() string {
+	return e.reason
+}
+
+func mismatchf(format string, args ...any) error {
+	return patternMismatch{fmt.Sprintf(format, args...)}
+}
+
+// describeValue formats a value for the reason of a mismatch
+func describeValue(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// matchLiteral matches a value equal to the expected one
+func matchLiteral(v, expected any) error {
+	if !isEqual(v, expected) {
+		return mismatchf(`expected %s, got %s`, describeValue(expected), describeValue(v))
+	}
+
+	return nil
+}
+
+// matchPattern matches a value against a pattern, the values of the variables
+// bound by the pattern are added to bindings. When the value doesn't match
+// the error is a patternMismatch. Pinned values like "$x" are evaluated in
+// the given context.
+func matchPattern(pattern Node, v any, ctx *Context, bindings map[string]any) error {
+	switch pattern.Type() {
+	case IdentifierNode:
+		name := pattern.Metadata()["Value"].(string)
+		if literal, ok := patternLiterals[name]; ok {
+			return matchLiteral(v, literal)
+		}
+		if name != "_" {
+			bindings[name] = v
+		}
+
+		return nil
+
+	case IntegerNode, FloatNode, DurationNode, StringNode:
+		return matchLiteral(v, pattern.Metadata()["Value"])
+
+	case RegexNode:
+		re := pattern.Metadata()["Value"].(*regexp.Regexp)
+		if s, ok := v.(string); !ok || !re.MatchString(s) {
+			return mismatchf(`expected string matching r"%s", got %s`, re, describeValue(v))
+		}
+
+		return nil
+
+	case QuotedExpressionNode:
+		if inner := pattern.Children()[0]; inner.Type() == IdentifierNode {
+			return matchLiteral(v, Intern(inner.Metadata()["Value"].(string)))
+		}
+
+	case UnquoteExpressionNode:
+		pinned, err := eval(pattern.Children()[0], ctx)
+		if err != nil {
+			return err
+		}
+
+		return matchLiteral(v, pinned)
+
+	case ParenthesisNode:
+		name, typeAst, ok := typeAnnotation(pattern.Children()[0])
+		if !ok {
+			return matchPattern(pattern.Children()[0], v, ctx, bindings)
+		}
+
+		typ, err := parseTypeExpression(typeAst)
+		if err != nil {
+			return err
+		}
+		if actual := TypeOf(v); !isAssignable(actual, typ) {
+			return mismatchf(`expected value of type %v, got %v`, typ, actual)
+		}
+
+		return matchPattern(name, v, ctx, bindings)
+
+	case ListNode:
+		list, ok := v.(*List)
+		if !ok {
+			return mismatchf(`expected list, got %v`, TypeOf(v))
+		}
+		if list.Len() != len(pattern.Children()) {
+			return mismatchf(`expected list of %d elements, got %d`, len(pattern.Children()), list.Len())
+		}
+
+		for i, item := range pattern.Children() {
+			if err := matchPattern(item, list.items[i], ctx, bindings); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case BlockNode:
+		fields, err := recordFields(pattern)
+		if err != nil {
+			return err
+		}
+
+		m, ok := v.(*Map)
+		if !ok {
+			return mismatchf(`expected map, got %v`, TypeOf(v))
+		}
+
+		for _, field := range fields {
+			name := field.Metadata()["Value"].(string)
+
+			value, ok := m.Get(name)
+			if !ok {
+				return mismatchf(`missing key "%s"`, name)
+			}
+
+			bindings[name] = value
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf(`invalid pattern`)
+}
+
+// destructure evaluates a declaration "<Pattern> := <Expression>" binding all
+// the variables of the pattern in the context
+func destructure(pattern Node, v any, ctx *Context, constant bool) error {
+	bindings := map[string]any{}
+
+	if err := matchPattern(pattern, v, ctx, bindings); err != nil {
+		if _, ok := err.(patternMismatch); ok {
+			return fmt.Errorf(`cannot destructure: %v`, err)
+		}
+
+		return err
+	}
+
+	for _, name := range patternBindings(pattern) {
+		name := name.Metadata()["Value"].(string)
+
+		var err error
+		if constant {
+			err = ctx.SetConst(name, bindings[name])
+		} else {
+			err = ctx.Set(name, bindings[name])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evalMatch evaluates the body of the first arm whose pattern matches the
+// value and whose guard is truthy, the variables bound by the pattern are
+// visible only in the guard and in the body of the arm
+func evalMatch(v any, arms []matchArm, ctx *Context) (any, error) {
+	for _, arm := range arms {
+		bindings := map[string]any{}
+
+		if err := matchPattern(arm.Pattern, v, ctx, bindings); err != nil {
+			// errors of pinned values are not mismatches even when they come
+			// from a failed destructuring, so they are not unwrapped
+			if _, ok := err.(patternMismatch); ok {
+				continue
+			}
+
+			return nil, err
+		}
+
+		local := ctx.child()
+		if err := local.state.alloc(scopeAllocSize); err != nil {
+			return nil, err
+		}
+		for name, value := range bindings {
+			local.Bindings[name] = value
+		}
+
+		if arm.Guard != nil {
+			guard, err := eval(arm.Guard, local)
+			if err != nil {
+				return nil, err
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		if arm.Body.Type() == BlockNode {
+			return evalBody(arm.Body, local)
+		}
+
+		return eval(arm.Body, local)
+	}
+
+	return nil, fmt.Errorf(`no pattern matches value of type %v`, TypeOf(v))
+}
@@ -0,0 +1,265 @@
+package ergolas_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleParse_destructuring() {
+	tokens, err := ergolas.Tokenize(`[a {x y}] := [1, point]`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, err := ergolas.ParseExpression(tokens)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ergolas.PrintAST(node)
+
+	// Output:
+	// - Binary
+	//   - List
+	//     - Identifier { Value: "a" }
+	//     - Block
+	//       - FunctionCall
+	//         - Identifier { Value: "x" }
+	//         - Identifier { Value: "y" }
+	//   - Operator { Value: ":=" }
+	//   - List
+	//     - Integer { Value: "1" }
+	//     - Identifier { Value: "point" }
+}
+
+func ExampleEvaluate_match() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithStrings(), ergolas.WithMath(), ergolas.WithRegex())
+
+	_, err := evaluateIn(ctx, `
+		origin := 0
+		describe := fn shape {
+			match shape {
+				[:circle 0] -> "a point"
+				[:circle r] if r > 10 -> "a big circle"
+				[:circle r] -> "a circle of radius " + (str r)
+				[:rect w h] if w == h -> "a square"
+				[:rect (w :: Int) (h :: Int)] -> "a rectangle"
+				[$origin _ _] -> "something at the origin"
+				{text} -> "a match of " + text
+				_ -> "something else"
+			}
+		}
+
+		println (describe [:circle 0])
+		println (describe [:circle 20])
+		println (describe [:circle 2])
+		println (describe [:rect 3 3])
+		println (describe [:rect 3 4])
+		println (describe [0 1 2])
+		println (describe (regex.match r"\d+" "abc 123"))
+		println (describe "circle")
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// a point
+	// a big circle
+	// a circle of radius 2
+	// a square
+	// a rectangle
+	// something at the origin
+	// a match of 123
+	// something else
+}
+
+func TestDestructuring(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `
+		[a, [b c]] := [1, [2 3]]
+		[_ d 4] := ["skip" 4 4]
+		[{text} n] := [(regex.match r"\d+" "a12") 3]
+		{start end} := (regex.match r"b+" "abbc")
+		[e f] :: List := [5 6]
+
+		[a b c d (str n) text (str (end - start)) e f]
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := fmt.Sprint(result); s != `[1 2 3 4 "3" "12" "2" 5 6]` {
+		t.Errorf("unexpected bindings %s", s)
+	}
+
+	_, err = evaluateIn(ergolas.NewRootContext(), `const [a b] := [1 2]; a = 3`)
+	if err == nil || err.Error() != `cannot assign to constant "a"` {
+		t.Errorf("expected constant error, got %v", err)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	failures := map[string]string{
+		`[a b] := [1 2 3]`:                    `cannot destructure: expected list of 2 elements, got 3`,
+		`[a [b c]] := [1 [2]]`:                `cannot destructure: expected list of 2 elements, got 1`,
+		`[a b] := "ab"`:                       `cannot destructure: expected list, got String`,
+		`[a 3] := [1 2]`:                      `cannot destructure: expected 3, got 2`,
+		`[a "x"] := [1 "y"]`:                  `cannot destructure: expected "x", got "y"`,
+		`[a :open] := [1 :closed]`:            `cannot destructure: expected :open, got :closed`,
+		`[(a :: String) b] := [1 2]`:          `cannot destructure: expected value of type String, got Int`,
+		`{missing} := (regex.match r"b" "b")`: `cannot destructure: missing key "missing"`,
+		`{text} := [1 2]`:                     `cannot destructure: expected map, got List`,
+		`{"key"} := (regex.match r"b" "b")`:   `expected field name in record pattern`,
+		`[a (b + 1)] := [1 2]`:                `invalid pattern`,
+	}
+
+	for source, expected := range failures {
+		_, err := evaluateIn(ergolas.NewRootContext(), source)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestMatchLiterals(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `
+		[
+			(match 1 { 1 -> "one"; _ -> "other" })
+			(match 2 { 1 -> "one"; _ -> "other" })
+			(match 2.0 { 2 -> "two" })
+			(match "b" { "a" -> 1; "b" -> 2 })
+			(match 90s { 1m30s -> "duration" })
+			(match :open { :closed -> 1; :open -> 2 })
+			(match nil { false -> 1; nil -> 2 })
+			(match true { true -> 1 })
+			(match "v1.2" { r"^v\d" -> "version" })
+		]
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := fmt.Sprint(result); s != `["one" "other" "two" 2 "duration" 2 2 1 "version"]` {
+		t.Errorf("unexpected results %s", s)
+	}
+}
+
+func TestMatchGuardsAndBindings(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `
+		x := 2
+		[
+			(match 5 { n if n > 3 -> n * 2; n -> n })
+			(match 2 { n if n > 3 -> n * 2; n -> n })
+			(match 3 { n if (n % 2) == 0 -> "even"; _ -> "odd" })
+			(match "s" { (n :: Int) -> "int"; (s :: String) -> "string" })
+			(match [1 [2 3]] { [a [b c]] -> a + b + c })
+			(match [1 2] { [] -> "empty"; [y] -> "one"; [y z] -> "two" })
+			(match 2 { $x -> "pinned" })
+			(match 3 { $x -> "pinned"; y -> y })
+			(match 1 { n -> { m := n + 1; m * 2 } })
+			(match 5 { x -> x })
+			x
+		]
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := fmt.Sprint(result); s != `[10 2 "odd" "string" 6 "two" "pinned" 3 4 5 2]` {
+		t.Errorf("unexpected results %s", s)
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	failures := map[string]string{
+		`match 1 { 2 -> "two" }`:               `no pattern matches value of type Int`,
+		`match 1 { n -> missing }`:             `unbound variable "missing"`,
+		`match 1 { (n + 1) -> n }`:             `invalid pattern`,
+		`match 1 { n if (call missing) -> n }`: `unbound variable "missing"`,
+		// a failed destructuring while evaluating a pinned value is an error
+		// and not a mismatch of the arm
+		`f := fn { [a] := [1 2]; a }; match 1 { $(call f) -> 1; _ -> 2 }`: `cannot destructure: expected list of 1 elements, got 2`,
+	}
+
+	for source, expected := range failures {
+		_, err := evaluateIn(ergolas.NewRootContext(), source)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error %q, got %v", source, expected, err)
+		}
+	}
+}
+
+func TestListLiteral(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `x := 2; [1, x "three" (x * 2) [x]
+		x + 1]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := fmt.Sprint(result); s != `[1 2 "three" 4 [2] 3]` {
+		t.Errorf("unexpected list %s", s)
+	}
+}
+
+func TestResolveMatch(t *testing.T) {
+	diagnostics := resolveSource(t, `
+		limit := 10
+		[a b] := [1 2]
+		println (match a {
+			[x _] if x > limit -> x
+			{text} -> text
+			$b -> b
+			n -> fn { n + later }
+		})
+		later := 1
+	`)
+
+	if len(diagnostics) > 0 {
+		t.Fatalf("expected no diagnostics, got %v", diagnostics)
+	}
+
+	source := `
+		f := fn {
+			[a b] := [1 2]
+			match a { x -> y; z -> 1 }
+		}
+	`
+	messages := []string{}
+	for _, d := range resolveSource(t, source) {
+		messages = append(messages, d.Format(source))
+	}
+
+	expected := []string{
+		`[3:7] warning: "b" declared and not used`,
+		`[4:14] warning: "x" declared and not used`,
+		`[4:19] error: undefined variable "y"`,
+		`[4:22] warning: "z" declared and not used`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+func TestCheckMatch(t *testing.T) {
+	source := `
+		x :: Int := match 1 { 1 -> "one"; _ -> "other" }
+		y :: Int := match 1 { 1 -> 1; n -> "other" }
+		match "s" { (s :: String) -> s + 1 }
+		[a b] := [1 2]
+		a + "one"
+	`
+
+	messages := checkSource(t, source)
+
+	expected := []string{
+		`[2:15] error: cannot assign value of type String to "x" of type Int`,
+		`[4:32] error: cannot apply operator "+" to types String and Int`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -19,12 +20,48 @@ var (
 	ParenthesisNode       NodeType = "Parenthesis"
 	IdentifierNode        NodeType = "Identifier"
 	BlockNode             NodeType = "Block"
+	ListNode              NodeType = "List"
 	IntegerNode           NodeType = "Integer"
 	FloatNode             NodeType = "Float"
+	DurationNode          NodeType = "Duration"
 	StringNode            NodeType = "String"
+	RegexNode             NodeType = "Regex"
 	OperatorNode          NodeType = "Operator"
 )
 
+// NodeTypes lists all node types produced by the parser
+var NodeTypes = []NodeType{
+	ProgramNode,
+	ExpressionsNode,
+	FunctionCallNode,
+	BinaryExpressionNode,
+	UnaryExpressionNode,
+	QuotedExpressionNode,
+	UnquoteExpressionNode,
+	PropertyAccessNode,
+	ParenthesisNode,
+	IdentifierNode,
+	BlockNode,
+	ListNode,
+	IntegerNode,
+	FloatNode,
+	DurationNode,
+	StringNode,
+	RegexNode,
+	OperatorNode,
+}
+
+// ParseError is an error found while parsing, Location is the offset in the
+// source of the token where the error happened
+type ParseError struct {
+	Location int
+	Message  string
+}
+
+func (e ParseError) Error() string {
+	return e.Message
+}
+
 type parser struct {
 	tokens []Token
 	cursor int
@@ -52,12 +89,24 @@ func (p *parser) advance() Token {
 	return p.tokens[p.cursor-1]
 }
 
+// errorf returns a ParseError located at the current token
+func (p *parser) errorf(format string, args ...any) error {
+	location := 0
+	if !p.done() {
+		location = p.peek().Location
+	} else if len(p.tokens) > 0 {
+		location = tokenSpan(p.tokens[len(p.tokens)-1]).End
+	}
+
+	return ParseError{location, fmt.Sprintf(format, args...)}
+}
+
 func (p *parser) expectValue(value string) error {
 	if p.done() {
-		return fmt.Errorf(`expected "%s" but got eof`, value)
+		return p.errorf(`expected "%s" but got eof`, value)
 	}
 	if p.peek().Value != value {
-		return fmt.Errorf(`expected "%s" but got "%s"`, value, p.peek().Value)
+		return p.errorf(`expected "%s" but got "%s"`, value, p.peek().Value)
 	}
 	p.advance()
 	return nil
@@ -65,14 +114,42 @@ func (p *parser) expectValue(value string) error {
 
 func (p *parser) expectType(typ TokenType) (Token, error) {
 	if p.done() {
-		return Token{}, fmt.Errorf(`expected %v but got eof`, typ)
+		return Token{}, p.errorf(`expected %v but got eof`, typ)
 	}
 	if p.peek().Type != typ {
-		return Token{}, fmt.Errorf(`expected %v but got %v`, typ, p.peek().Type)
+		return Token{}, p.errorf(`expected %v but got %v`, typ, p.peek().Type)
 	}
 	return p.advance(), nil
 }
 
+// tokenSpan returns the span of source covered by a single token
+func tokenSpan(t Token) Span {
+	return Span{t.Location, t.Location + len(t.Value)}
+}
+
+// spanFrom returns the span of source covered by the tokens consumed since the
+// given cursor position
+func (p *parser) spanFrom(start int) Span {
+	if start >= p.cursor {
+		if start < len(p.tokens) {
+			return Span{p.tokens[start].Location, p.tokens[start].Location}
+		}
+		if len(p.tokens) > 0 {
+			end := tokenSpan(p.tokens[len(p.tokens)-1]).End
+			return Span{end, end}
+		}
+		return Span{}
+	}
+
+	return Span{p.tokens[start].Location, tokenSpan(p.tokens[p.cursor-1]).End}
+}
+
+// spanAfter returns the span going from the start of the given node to the
+// last consumed token
+func (p *parser) spanAfter(n Node) Span {
+	return Span{n.Span().Start, tokenSpan(p.tokens[p.cursor-1]).End}
+}
+
 func (p *parser) advanceLines() {
 	for !p.done() && p.peek().Type == NewlineToken {
 		p.advance()
@@ -86,12 +163,18 @@ func (p *parser) parse() (Node, error) {
 	p.log(`enter parse()`, +1)
 	defer p.log(`exit parse()`, -1)
 
+	start := p.cursor
+
 	statements, err := p.parseStatements()
 	if err != nil {
 		return nil, err
 	}
 
-	return listNode{ProgramNode, statements}, nil
+	if !p.done() {
+		return nil, p.errorf(`unexpected "%s"`, p.peek().Value)
+	}
+
+	return listNode{ProgramNode, statements, p.spanFrom(start)}, nil
 }
 
 // parseExpressions has grammar
@@ -101,12 +184,18 @@ func (p *parser) parseExpressions() (Node, error) {
 	p.log(`enter parse()`, +1)
 	defer p.log(`exit parse()`, -1)
 
+	start := p.cursor
+
 	statements, err := p.parseStatements()
 	if err != nil {
 		return nil, err
 	}
 
-	return listNode{ExpressionsNode, statements}, nil
+	if !p.done() {
+		return nil, p.errorf(`unexpected "%s"`, p.peek().Value)
+	}
+
+	return listNode{ExpressionsNode, statements, p.spanFrom(start)}, nil
 }
 
 // parseStatements has grammar
@@ -147,12 +236,12 @@ func (p *parser) parseExpression() (Node, error) {
 
 // parseRightBinaryExpression has grammar
 //
-//	<RightBinaryExpression> ::= <IntermediateExpression> ( ROperator <RightBinaryExpression>)?
+//	<RightBinaryExpression> ::= <PipeExpression> ( ROperator <RightBinaryExpression>)?
 func (p *parser) parseRightBinaryExpression() (Node, error) {
 	p.log(`enter parseRightBinaryExpression()`, +1)
 	defer p.log(`exit parseRightBinaryExpression()`, -1)
 
-	lhs, err := p.parseIntermediate()
+	lhs, err := p.parsePipe()
 	if err != nil {
 		return nil, err
 	}
@@ -164,14 +253,52 @@ func (p *parser) parseRightBinaryExpression() (Node, error) {
 			return nil, err
 		}
 
-		return listNode{BinaryExpressionNode, []Node{lhs, leafNode{OperatorNode, t.Value}, rhs}}, nil
+		// A type annotation binds tighter than an assignment, so "x :: T := v"
+		// gets rotated to "(x :: T) := v"
+		if t.Value == "::" && rhs.Type() == BinaryExpressionNode && rhs.Children()[1].Metadata()["Value"] == ":=" {
+			typ, assign, value := rhs.Children()[0], rhs.Children()[1], rhs.Children()[2]
+			annotated := listNode{BinaryExpressionNode, []Node{lhs, leafNode{OperatorNode, t.Value, tokenSpan(t)}, typ}, Span{lhs.Span().Start, typ.Span().End}}
+			return listNode{BinaryExpressionNode, []Node{annotated, assign, value}, p.spanAfter(lhs)}, nil
+		}
+
+		return listNode{BinaryExpressionNode, []Node{lhs, leafNode{OperatorNode, t.Value, tokenSpan(t)}, rhs}, p.spanAfter(lhs)}, nil
+	}
+
+	return lhs, nil
+}
+
+// parsePipe has grammar
+//
+//	<PipeExpression> ::= <IntermediateExpression> ( "|>" <IntermediateExpression> )*
+//
+// unlike the other right operators pipes are left associative so that
+// "x |> f |> g" is "(x |> f) |> g"
+func (p *parser) parsePipe() (Node, error) {
+	p.log(`enter parsePipe()`, +1)
+	defer p.log(`exit parsePipe()`, -1)
+
+	lhs, err := p.parseIntermediate()
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.done() && p.peek().Type == ROperatorToken && p.peek().Value == "|>" {
+		t := p.advance()
+		p.advanceLines()
+
+		rhs, err := p.parseIntermediate()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = listNode{BinaryExpressionNode, []Node{lhs, leafNode{OperatorNode, t.Value, tokenSpan(t)}, rhs}, p.spanAfter(lhs)}
 	}
 
 	return lhs, nil
 }
 
 var functionCallTerminators = map[string]struct{}{
-	";": {}, ")": {}, "]": {}, "}": {}, ":=": {}, "::": {}, "<-": {}, "->": {}, "|>": {},
+	";": {}, ")": {}, "]": {}, "}": {}, ":=": {}, "=": {}, "::": {}, "<-": {}, "->": {}, "|>": {},
 }
 
 func isFunctionCallTerminator(t Token) bool {
@@ -216,7 +343,7 @@ func (p *parser) parseIntermediate() (Node, error) {
 	}
 
 	if len(nodes) > 1 {
-		return listNode{FunctionCallNode, nodes}, nil
+		return listNode{FunctionCallNode, nodes, p.spanAfter(node)}, nil
 	}
 
 	return node, nil
@@ -236,7 +363,7 @@ func (p *parser) parseLeftBinaryExpression(lhs Node) (Node, error) {
 			return nil, err
 		}
 
-		lhs = listNode{BinaryExpressionNode, []Node{lhs, leafNode{OperatorNode, t.Value}, rhs}}
+		lhs = listNode{BinaryExpressionNode, []Node{lhs, leafNode{OperatorNode, t.Value, tokenSpan(t)}, rhs}, p.spanAfter(lhs)}
 	}
 
 	return lhs, nil
@@ -263,8 +390,8 @@ func (p *parser) parsePropertyOrValue() (Node, error) {
 
 		node = &listNode{PropertyAccessNode, []Node{
 			node,
-			leafNode{IdentifierNode, t.Value},
-		}}
+			leafNode{IdentifierNode, t.Value, tokenSpan(t)},
+		}, p.spanAfter(node)}
 	}
 
 	return node, nil
@@ -274,11 +401,16 @@ func (p *parser) parsePropertyOrValue() (Node, error) {
 //
 //	<Value> ::= <ParensExpression>
 //	          | <BlockExpression>
+//	          | <List>
 //	          | <Identifier>
 //	          | <Integer>
 //	          | <Float>
+//	          | <Duration>
 //	          | <String>
+//	          | <Regex>
 //	          | <QuotedExpression>
+//	          | <UnquotedExpression>
+//	          | <Receive>
 func (p *parser) parseValue() (Node, error) {
 	p.log(`enter parseValue()`, +1)
 	defer p.log(`exit parseValue()`, -1)
@@ -289,6 +421,9 @@ func (p *parser) parseValue() (Node, error) {
 	if n, err := p.parseBlock(); err == nil {
 		return n, nil
 	}
+	if !p.done() && p.peek().Value == "[" {
+		return p.parseList()
+	}
 	if n, err := p.parseIdentifier(); err == nil {
 		return n, nil
 	}
@@ -298,17 +433,31 @@ func (p *parser) parseValue() (Node, error) {
 	if n, err := p.parseFloat(); err == nil {
 		return n, nil
 	}
-	if n, err := p.parseString(); err == nil {
+	if n, err := p.parseDuration(); err == nil {
 		return n, nil
 	}
+	if !p.done() && p.peek().Type == StringToken {
+		// the only way a string can fail to parse is an invalid escape
+		return p.parseString()
+	}
+	if !p.done() && p.peek().Type == RegexToken {
+		return p.parseRegex()
+	}
 	if n, err := p.parseQuoted(); err == nil {
 		return n, nil
 	}
 	if n, err := p.parseUnquoted(); err == nil {
 		return n, nil
 	}
+	if !p.done() && p.peek().Value == "<-" {
+		return p.parseReceive()
+	}
+
+	if p.done() {
+		return nil, p.errorf(`expected value but got eof`)
+	}
 
-	return nil, fmt.Errorf(`expected value but got %s`, p.peek().Type)
+	return nil, p.errorf(`expected value but got %s`, p.peek().Type)
 }
 
 // parseParens has grammar
@@ -318,6 +467,8 @@ func (p *parser) parseParens() (Node, error) {
 	p.log(`enter parseParens()`, +1)
 	defer p.log(`exit parseParens()`, -1)
 
+	start := p.cursor
+
 	if err := p.expectValue(`(`); err != nil {
 		return nil, err
 	}
@@ -331,7 +482,7 @@ func (p *parser) parseParens() (Node, error) {
 		return nil, err
 	}
 
-	return listNode{ParenthesisNode, []Node{inner}}, nil
+	return listNode{ParenthesisNode, []Node{inner}, p.spanFrom(start)}, nil
 }
 
 // parseBlock has grammar
@@ -341,6 +492,8 @@ func (p *parser) parseBlock() (Node, error) {
 	p.log(`enter parseBlock()`, +1)
 	defer p.log(`exit parseBlock()`, -1)
 
+	start := p.cursor
+
 	if err := p.expectValue(`{`); err != nil {
 		return nil, err
 	}
@@ -358,7 +511,51 @@ func (p *parser) parseBlock() (Node, error) {
 		return nil, err
 	}
 
-	return listNode{BlockNode, statements}, nil
+	return listNode{BlockNode, statements, p.spanFrom(start)}, nil
+}
+
+// parseList has grammar
+//
+//	<List> ::= "[" ( <PropertyOrValue> <LeftBinaryExpression> ","? )* "]"
+//
+// the items are parsed like the arguments of a function call, so a call
+// inside a list needs parentheses
+func (p *parser) parseList() (Node, error) {
+	p.log(`enter parseList()`, +1)
+	defer p.log(`exit parseList()`, -1)
+
+	start := p.cursor
+
+	if err := p.expectValue(`[`); err != nil {
+		return nil, err
+	}
+
+	items := []Node{}
+
+	p.advanceLines()
+	for !p.done() && p.peek().Value != "]" {
+		itemBase, err := p.parsePropertyOrValue()
+		if err != nil {
+			return nil, err
+		}
+		item, err := p.parseLeftBinaryExpression(itemBase)
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.done() && p.peek().Value == "," {
+			p.advance()
+		}
+		p.advanceLines()
+
+		items = append(items, item)
+	}
+
+	if err := p.expectValue(`]`); err != nil {
+		return nil, err
+	}
+
+	return listNode{ListNode, items, p.spanFrom(start)}, nil
 }
 
 // parseQuoted has grammar
@@ -368,6 +565,8 @@ func (p *parser) parseQuoted() (Node, error) {
 	p.log(`enter parseQuoted()`, +1)
 	defer p.log(`exit parseQuoted()`, -1)
 
+	start := p.cursor
+
 	if _, err := p.expectType(QuoteToken); err != nil {
 		return nil, err
 	}
@@ -377,7 +576,7 @@ func (p *parser) parseQuoted() (Node, error) {
 		return nil, err
 	}
 
-	return listNode{QuotedExpressionNode, []Node{inner}}, nil
+	return listNode{QuotedExpressionNode, []Node{inner}, p.spanFrom(start)}, nil
 }
 
 // parseUnquoted has grammar
@@ -387,6 +586,8 @@ func (p *parser) parseUnquoted() (Node, error) {
 	p.log(`enter parseUnquoted()`, +1)
 	defer p.log(`exit parseUnquoted()`, -1)
 
+	start := p.cursor
+
 	if _, err := p.expectType(UnquoteToken); err != nil {
 		return nil, err
 	}
@@ -396,7 +597,29 @@ func (p *parser) parseUnquoted() (Node, error) {
 		return nil, err
 	}
 
-	return listNode{UnquoteExpressionNode, []Node{inner}}, nil
+	return listNode{UnquoteExpressionNode, []Node{inner}, p.spanFrom(start)}, nil
+}
+
+// parseReceive has grammar
+//
+//	<Receive> ::= "<-" <PropertyOrValue>
+func (p *parser) parseReceive() (Node, error) {
+	p.log(`enter parseReceive()`, +1)
+	defer p.log(`exit parseReceive()`, -1)
+
+	start := p.cursor
+
+	t, err := p.expectType(ROperatorToken)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := p.parsePropertyOrValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return listNode{UnaryExpressionNode, []Node{leafNode{OperatorNode, t.Value, tokenSpan(t)}, inner}, p.spanFrom(start)}, nil
 }
 
 // parseInteger has grammar
@@ -416,7 +639,7 @@ func (p *parser) parseInteger() (Node, error) {
 		return nil, err
 	}
 
-	return leafNode{IntegerNode, value}, nil
+	return leafNode{IntegerNode, value, tokenSpan(t)}, nil
 }
 
 // parseIdentifier has grammar
@@ -431,7 +654,7 @@ func (p *parser) parseIdentifier() (Node, error) {
 		return nil, err
 	}
 
-	return leafNode{IdentifierNode, t.Value}, nil
+	return leafNode{IdentifierNode, t.Value, tokenSpan(t)}, nil
 }
 
 // parseFloat has grammar
@@ -451,7 +674,27 @@ func (p *parser) parseFloat() (Node, error) {
 		return nil, err
 	}
 
-	return leafNode{FloatNode, value}, nil
+	return leafNode{FloatNode, value, tokenSpan(t)}, nil
+}
+
+// parseDuration has grammar
+//
+//	<Duration> ::= Duration
+func (p *parser) parseDuration() (Node, error) {
+	p.log(`enter parseDuration()`, +1)
+	defer p.log(`exit parseDuration()`, -1)
+
+	t, err := p.expectType(DurationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := time.ParseDuration(t.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return leafNode{DurationNode, value, tokenSpan(t)}, nil
 }
 
 // parseString has grammar
@@ -466,6 +709,50 @@ func (p *parser) parseString() (Node, error) {
 		return nil, err
 	}
 
-	value := t.Value[1 : len(t.Value)-1] // TODO: fix escaped characters
-	return leafNode{StringNode, value}, nil
+	value, err := unescapeString(t.Value[1 : len(t.Value)-1])
+	if err != nil {
+		return nil, ParseError{t.Location, fmt.Sprintf(`invalid escape sequence in string %s`, t.Value)}
+	}
+
+	return leafNode{StringNode, value, tokenSpan(t)}, nil
+}
+
+// parseRegex has grammar
+//
+//	<Regex> ::= Regex
+func (p *parser) parseRegex() (Node, error) {
+	p.log(`enter parseRegex()`, +1)
+	defer p.log(`exit parseRegex()`, -1)
+
+	t, err := p.expectType(RegexToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// only \" is an escape, all other backslashes belong to the pattern
+	pattern := strings.ReplaceAll(t.Value[2:len(t.Value)-1], `\"`, `"`)
+
+	value, err := compileRegex(pattern)
+	if err != nil {
+		return nil, ParseError{t.Location, err.Error()}
+	}
+
+	return leafNode{RegexNode, value, tokenSpan(t)}, nil
+}
+
+// unescapeString replaces the escape sequences of Go strings like "\n" or
+// "\u00e8", unlike Go strings can also span multiple lines
+func unescapeString(s string) (string, error) {
+	sb := &strings.Builder{}
+	for len(s) > 0 {
+		r, _, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteRune(r)
+		s = tail
+	}
+
+	return sb.String(), nil
 }
@@ -0,0 +1,322 @@
+package ergolas
+
+import (
+	"fmt"
+	"sort"
+)
+
+type binding struct {
+	name   string
+	span   Span
+	used   bool
+	global bool
+	// constant bindings are declared with "const" and can't be assigned
+	constant bool
+}
+
+type scope struct {
+	parent   *scope
+	global   bool
+	bindings map[string]*binding
+	order    []*binding
+
+	// pending holds the bodies of the functions and blocks defined in this
+	// scope, these get resolved only when the scope is closed so they can
+	// refer to names bound after them (e.g. for mutual recursion)
+	pending []func()
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, bindings: map[string]*binding{}}
+}
+
+func (s *scope) lookup(name string) (*binding, *scope) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if b, ok := cur.bindings[name]; ok {
+			return b, cur
+		}
+	}
+
+	return nil, nil
+}
+
+type resolver struct {
+	diagnostics []Diagnostic
+
+	// references maps the span of each identifier to the span of the
+	// declaration it refers to
+	references map[Span]Span
+}
+
+func (r *resolver) report(severity Severity, span Span, format string, args ...any) {
+	r.diagnostics = append(r.diagnostics, Diagnostic{severity, span, fmt.Sprintf(format, args...)})
+}
+
+// Resolve statically checks the name bindings of a program before running it.
+// The globals are the names the host defines in the root context used to
+// evaluate the program, usually just "ctx.Names()".
+//
+// It reports undefined variables, shadowed bindings, unused bindings,
+// assignments to undeclared variables or constants and assignments
+// overwriting globals. Top level bindings are never reported as
+// unused as the host can still read them after evaluation.
+func Resolve(node Node, globals []string) []Diagnostic {
+	return resolveProgram(node, globals).diagnostics
+}
+
+// References returns a map from the span of each identifier of the program to
+// the span of the declaration it refers to, identifiers referring to globals
+// are not included.
+func References(node Node, globals []string) map[Span]Span {
+	return resolveProgram(node, globals).references
+}
+
+func resolveProgram(node Node, globals []string) *resolver {
+	r := &resolver{references: map[Span]Span{}}
+
+	root := newScope(nil)
+	root.global = true
+	for _, name := range globals {
+		root.bindings[name] = &binding{name: name, global: true}
+	}
+
+	top := newScope(root)
+	r.resolve(node, top)
+	r.closeScope(top, false)
+
+	sort.SliceStable(r.diagnostics, func(i, j int) bool {
+		return r.diagnostics[i].Span.Start < r.diagnostics[j].Span.Start
+	})
+
+	return r
+}
+
+// closeScope resolves all function bodies still pending in this scope and
+// then reports unused bindings if requested
+func (r *resolver) closeScope(s *scope, reportUnused bool) {
+	for len(s.pending) > 0 {
+		body := s.pending[0]
+		s.pending = s.pending[1:]
+		body()
+	}
+
+	if !reportUnused {
+		return
+	}
+
+	for _, b := range s.order {
+		if !b.used {
+			r.report(SeverityWarning, b.span, `"%s" declared and not used`, b.name)
+		}
+	}
+}
+
+// declare binds a name in the given scope, if the name is already bound in
+// this same scope then this is just a reassignment of the previous binding.
+func (r *resolver) declare(s *scope, name string, span Span, reportShadowing bool, constant bool) {
+	if b, ok := s.bindings[name]; ok {
+		if b.constant {
+			r.report(SeverityError, span, `cannot assign to constant "%s"`, name)
+		}
+
+		r.references[span] = b.span
+		return
+	}
+
+	if b, owner := s.lookup(name); b != nil {
+		if owner.global {
+			r.report(SeverityWarning, span, `assignment to global "%s" provided by the host`, name)
+		} else if reportShadowing {
+			r.report(SeverityWarning, span, `declaration of "%s" shadows a previous declaration`, name)
+		}
+	}
+
+	b := &binding{name: name, span: span, constant: constant}
+	s.bindings[name] = b
+	s.order = append(s.order, b)
+	r.references[span] = span
+}
+
+func (r *resolver) resolve(node Node, s *scope) {
+	switch node.Type() {
+	case IdentifierNode:
+		name := node.Metadata()["Value"].(string)
+		b, _ := s.lookup(name)
+		if b == nil {
+			r.report(SeverityError, node.Span(), `undefined variable "%s"`, name)
+			return
+		}
+
+		b.used = true
+		if !b.global {
+			r.references[node.Span()] = b.span
+		}
+
+	case BinaryExpressionNode:
+		lhs := node.Children()[0]
+		op := node.Children()[1].Metadata()["Value"].(string)
+		rhs := node.Children()[2]
+
+		if def, ok := functionLiteral(node); ok {
+			r.resolveFunction(def.Params, def.Body, s)
+			return
+		}
+
+		if op == ":=" {
+			r.resolve(rhs, s)
+
+			if target, _, ok := typeAnnotation(lhs); ok {
+				lhs = target
+			}
+
+			lhs, constant := constDeclaration(lhs)
+			if isDestructuring(lhs) {
+				r.resolveUnquoted(lhs, s)
+				for _, name := range patternBindings(lhs) {
+					r.declare(s, name.Metadata()["Value"].(string), name.Span(), true, constant)
+				}
+				return
+			}
+			if lhs.Type() != IdentifierNode {
+				r.report(SeverityError, lhs.Span(), `expected identifier on left side of assignment`)
+				return
+			}
+
+			r.declare(s, lhs.Metadata()["Value"].(string), lhs.Span(), true, constant)
+			return
+		}
+
+		if op == "=" {
+			r.resolve(rhs, s)
+
+			if lhs.Type() != IdentifierNode {
+				r.report(SeverityError, lhs.Span(), `expected identifier on left side of assignment`)
+				return
+			}
+
+			name := lhs.Metadata()["Value"].(string)
+			b, owner := s.lookup(name)
+			switch {
+			case b == nil:
+				r.report(SeverityError, lhs.Span(), `cannot assign to undeclared variable "%s"`, name)
+			case b.constant:
+				r.report(SeverityError, lhs.Span(), `cannot assign to constant "%s"`, name)
+			case owner.global:
+				r.report(SeverityWarning, lhs.Span(), `assignment to global "%s" provided by the host`, name)
+			default:
+				r.references[lhs.Span()] = b.span
+			}
+			return
+		}
+
+		if op == "::" {
+			// the type is not an expression
+			r.resolve(lhs, s)
+			return
+		}
+
+		if op == "|>" {
+			// the placeholder "_" is not a variable
+			callee, args, _ := pipeCall(rhs)
+
+			r.resolve(lhs, s)
+			r.resolve(callee, s)
+			for _, arg := range args {
+				r.resolve(arg, s)
+			}
+			return
+		}
+
+		r.resolve(lhs, s)
+		r.resolve(rhs, s)
+
+	case FunctionCallNode:
+		if def, ok := functionLiteral(node); ok {
+			r.resolveFunction(def.Params, def.Body, s)
+			return
+		}
+		if subject, arms, ok := matchExpression(node); ok {
+			r.resolve(subject, s)
+			for _, arm := range arms {
+				r.resolveMatchArm(arm, s)
+			}
+			return
+		}
+
+		for _, n := range node.Children() {
+			r.resolve(n, s)
+		}
+
+	case BlockNode:
+		r.resolveFunction(nil, node, s)
+
+	case PropertyAccessNode:
+		// the property name is not a variable
+		r.resolve(node.Children()[0], s)
+
+	case QuotedExpressionNode:
+		r.resolveUnquoted(node.Children()[0], s)
+
+	default:
+		for _, n := range node.Children() {
+			r.resolve(n, s)
+		}
+	}
+}
+
+// resolveFunction schedules the resolution of a function body in a new scope
+// containing its parameters
+func (r *resolver) resolveFunction(params []functionParam, body Node, s *scope) {
+	s.pending = append(s.pending, func() {
+		inner := newScope(s)
+		for _, param := range params {
+			inner.bindings[param.Name] = &binding{name: param.Name, span: param.Span}
+			r.references[param.Span] = param.Span
+		}
+
+		for _, n := range body.Children() {
+			r.resolve(n, inner)
+		}
+
+		r.closeScope(inner, true)
+	})
+}
+
+// resolveUnquoted only resolves the unquoted parts of a quoted expression
+func (r *resolver) resolveUnquoted(node Node, s *scope) {
+	if node.Type() == UnquoteExpressionNode {
+		r.resolve(node.Children()[0], s)
+		return
+	}
+
+	for _, n := range node.Children() {
+		r.resolveUnquoted(n, s)
+	}
+}
+
+// resolveMatchArm resolves an arm of a match in a new scope containing the
+// variables bound by its pattern, pinned values are resolved in the outer
+// scope
+func (r *resolver) resolveMatchArm(arm matchArm, s *scope) {
+	r.resolveUnquoted(arm.Pattern, s)
+
+	inner := newScope(s)
+	for _, name := range patternBindings(arm.Pattern) {
+		r.declare(inner, name.Metadata()["Value"].(string), name.Span(), false, false)
+	}
+
+	if arm.Guard != nil {
+		r.resolve(arm.Guard, inner)
+	}
+
+	body := []Node{arm.Body}
+	if arm.Body.Type() == BlockNode {
+		body = arm.Body.Children()
+	}
+	for _, n := range body {
+		r.resolve(n, inner)
+	}
+
+	// functions in the arm can refer to names bound later in the outer scope
+	s.pending = append(s.pending, func() { r.closeScope(inner, true) })
+}
@@ -0,0 +1,100 @@
+package ergolas_test
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func resolveSource(t *testing.T, source string) []ergolas.Diagnostic {
+	t.Helper()
+
+	tokens, err := ergolas.Tokenize(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ergolas.Resolve(node, ergolas.NewRootContext().Names())
+}
+
+func ExampleResolve() {
+	source := `
+		a := 1
+		println a b
+		println := 2
+		f := fn x {
+			y := x
+			a := g x
+		}
+		g := fn x { x }
+	`
+
+	tokens, err := ergolas.Tokenize(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, d := range ergolas.Resolve(node, ergolas.NewRootContext().Names()) {
+		fmt.Println(d.Format(source))
+	}
+
+	// Output:
+	// [3:13] error: undefined variable "b"
+	// [4:3] warning: assignment to global "println" provided by the host
+	// [6:4] warning: "y" declared and not used
+	// [7:4] warning: declaration of "a" shadows a previous declaration
+	// [7:4] warning: "a" declared and not used
+}
+
+func TestResolveClean(t *testing.T) {
+	diagnostics := resolveSource(t, `
+		x := 1
+		y := x + 2
+		println (fn a b { a + b + y }) :(z + $x)
+	`)
+
+	if len(diagnostics) > 0 {
+		t.Fatalf("expected no diagnostics, got %v", diagnostics)
+	}
+}
+
+func TestResolveScopes(t *testing.T) {
+	diagnostics := resolveSource(t, `
+		f := fn { inner := 1; inner }
+		println inner
+	`)
+
+	if len(diagnostics) != 1 || diagnostics[0].Message != `undefined variable "inner"` {
+		t.Fatalf("expected inner to be undefined outside its block, got %v", diagnostics)
+	}
+}
+
+func TestResolvePipelineAndLambdas(t *testing.T) {
+	diagnostics := resolveSource(t, `
+		double := x -> x * 2
+		add := (a b) -> a + b
+		"a-b" |> split "-" |> join "+" _ |> println
+		println (3 |> double |> add 1)
+	`)
+
+	if len(diagnostics) > 0 {
+		t.Fatalf("expected no diagnostics, got %v", diagnostics)
+	}
+
+	diagnostics = resolveSource(t, `f := x -> y`)
+	if len(diagnostics) != 1 || diagnostics[0].Message != `undefined variable "y"` {
+		t.Fatalf("expected y to be undefined, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,151 @@
+package ergolas
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// EvalOptions limits the resources a script can use, the zero value of each
+// field means no limit.
+type EvalOptions struct {
+	// MaxSteps is the maximum number of nodes evaluated
+	MaxSteps int64
+	// MaxDepth is the maximum number of nested function calls
+	MaxDepth int64
+	// MaxAllocations is the maximum estimated number of bytes allocated for
+	// strings, functions and scopes
+	MaxAllocations int64
+
+	// Context can be used to cancel the evaluation or set a deadline
+	Context context.Context
+}
+
+type LimitKind string
+
+var (
+	StepLimit       LimitKind = "step"
+	DepthLimit      LimitKind = "depth"
+	AllocationLimit LimitKind = "allocation"
+)
+
+// LimitError is returned when the evaluation exceeds one of the limits set
+// in EvalOptions
+type LimitError struct {
+	Limit LimitKind
+	Max   int64
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf(`%s limit exceeded (max %d)`, e.Limit, e.Max)
+}
+
+// ExitError is returned by the "exit" builtin, it is up to the host to decide
+// what to do with it, e.g. the REPL terminates the process with its code.
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf(`exit with code %d`, e.Code)
+}
+
+// estimated sizes in bytes of values without an obvious size
+const (
+	functionAllocSize = 64
+	scopeAllocSize    = 64
+	listItemAllocSize = 16
+	symbolAllocSize   = 64
+)
+
+// evalState keeps track of the resources used by an evaluation, a nil state
+// has no limits.
+type evalState struct {
+	opts EvalOptions
+
+	steps     atomic.Int64
+	depth     atomic.Int64
+	allocated atomic.Int64
+}
+
+func (s *evalState) step() error {
+	if s == nil {
+		return nil
+	}
+
+	if s.opts.Context != nil {
+		if err := s.opts.Context.Err(); err != nil {
+			return fmt.Errorf(`evaluation interrupted: %w`, err)
+		}
+	}
+
+	if steps := s.steps.Add(1); s.opts.MaxSteps > 0 && steps > s.opts.MaxSteps {
+		return LimitError{StepLimit, s.opts.MaxSteps}
+	}
+
+	return nil
+}
+
+// done returns a channel closed when the evaluation gets cancelled, the
+// channel is nil when there is nothing that can cancel it
+func (s *evalState) done() <-chan struct{} {
+	if s == nil || s.opts.Context == nil {
+		return nil
+	}
+
+	return s.opts.Context.Done()
+}
+
+// context returns the context that cancels the evaluation or a background
+// context if there is none
+func (s *evalState) context() context.Context {
+	if s == nil || s.opts.Context == nil {
+		return context.Background()
+	}
+
+	return s.opts.Context
+}
+
+func (s *evalState) enter() error {
+	if s == nil {
+		return nil
+	}
+
+	if depth := s.depth.Add(1); s.opts.MaxDepth > 0 && depth > s.opts.MaxDepth {
+		s.depth.Add(-1)
+		return LimitError{DepthLimit, s.opts.MaxDepth}
+	}
+
+	return nil
+}
+
+func (s *evalState) leave() {
+	if s != nil {
+		s.depth.Add(-1)
+	}
+}
+
+func (s *evalState) alloc(size int64) error {
+	if s == nil {
+		return nil
+	}
+
+	if allocated := s.allocated.Add(size); s.opts.MaxAllocations > 0 && allocated > s.opts.MaxAllocations {
+		return LimitError{AllocationLimit, s.opts.MaxAllocations}
+	}
+
+	return nil
+}
+
+// EvaluateWithOptions evaluates a node in the given context enforcing the
+// limits of the given options.
+func EvaluateWithOptions(node Node, ctx *Context, opts EvalOptions) (result any, err error) {
+	// a bug in the interpreter or in a builtin must not take down the host
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf(`internal error: %v`, r)
+		}
+	}()
+
+	return eval(node, ctx.evaluation(opts))
+}
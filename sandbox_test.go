@@ -0,0 +1,127 @@
+package ergolas_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aziis98/ergolas"
+)
+
+func TestEvalLimits(t *testing.T) {
+	forever := `loop := fn x { loop x }; loop 1`
+	growing := `grow := fn s { grow (s + s) }; grow "abcd"`
+
+	tests := []struct {
+		name   string
+		source string
+		opts   ergolas.EvalOptions
+		limit  ergolas.LimitKind
+	}{
+		{"steps", forever, ergolas.EvalOptions{MaxSteps: 1000}, ergolas.StepLimit},
+		{"depth", forever, ergolas.EvalOptions{MaxDepth: 100}, ergolas.DepthLimit},
+		{"allocations", growing, ergolas.EvalOptions{MaxAllocations: 1 << 20, MaxDepth: 1000}, ergolas.AllocationLimit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := evaluateIn(ergolas.NewRootContext(), test.source, test.opts)
+
+			var limitErr ergolas.LimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+				t.Fatalf("expected %s limit error, got %v", test.limit, err)
+			}
+		})
+	}
+}
+
+func TestEvalDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := evaluateIn(ergolas.NewRootContext(), `loop := fn x { loop x }; loop 1`, ergolas.EvalOptions{Context: ctx, MaxDepth: 1 << 20})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestExitError(t *testing.T) {
+	_, err := evaluateIn(ergolas.NewRootContext(), `exit 3; println "unreachable"`, ergolas.EvalOptions{})
+
+	var exitErr ergolas.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit error with code 3, got %v", err)
+	}
+}
+
+func TestEvalRuntimePanics(t *testing.T) {
+	for _, source := range []string{`1 / 0`, `5 % 0`, `f := fn { 1 / 0 }; call f`} {
+		_, err := evaluateIn(ergolas.NewRootContext(), source, ergolas.EvalOptions{})
+		if err == nil || err.Error() != "division by zero" {
+			t.Errorf("%s: expected division by zero, got %v", source, err)
+		}
+	}
+
+	// panics of the builtins provided by the host get returned as errors
+	ctx := ergolas.NewRootContext(ergolas.WithBindings(map[string]any{
+		"boom": func(args ...any) (any, error) { panic("boom") },
+	}))
+
+	tokens, err := ergolas.Tokenize(`boom 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ergolas.EvaluateWithOptions(node, ctx, ergolas.EvalOptions{}); err == nil || err.Error() != "internal error: boom" {
+		t.Errorf("expected internal error, got %v", err)
+	}
+}
+
+func TestEvalLimitsConcurrent(t *testing.T) {
+	ctx := ergolas.NewRootContext()
+	limited, err := parseSource(`loop := fn x { loop x }; loop 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlimited, err := parseSource(`total := 0; add := fn x { total = total + x }; add 1; add 2; total`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			_, err := ergolas.EvaluateWithOptions(limited, ctx, ergolas.EvalOptions{MaxSteps: 1000})
+			var limitErr ergolas.LimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.StepLimit {
+				t.Errorf("expected step limit error, got %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				if _, err := ergolas.EvaluateWithOptions(unlimited, ctx, ergolas.EvalOptions{}); err != nil {
+					t.Errorf("unexpected error %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// top level declarations still end up in the context
+	if _, err := ctx.GetKey("loop"); err != nil {
+		t.Errorf("expected loop to be declared, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package ergolas
+
+import "sync"
+
+// Symbol is the value of a quoted identifier like ":name", symbols are
+// interned so two symbols with the same name are the same pointer and can be
+// compared with "==" or used as keys of Go maps.
+type Symbol struct {
+	name string
+}
+
+// symbols holds all the symbols ever created, these are never released
+var symbols sync.Map
+
+// Intern returns the symbol with the given name. Symbols are never freed, so
+// interning names that come from untrusted input grows the memory of the
+// process without bound.
+func Intern(name string) *Symbol {
+	if s, ok := lookupSymbol(name); ok {
+		return s
+	}
+
+	s, _ := symbols.LoadOrStore(name, &Symbol{name})
+	return s.(*Symbol)
+}
+
+// lookupSymbol returns the symbol with the given name if it was already
+// interned
+func lookupSymbol(name string) (*Symbol, bool) {
+	s, ok := symbols.Load(name)
+	if !ok {
+		return nil, false
+	}
+
+	return s.(*Symbol), true
+}
+
+// Name returns the name of the symbol without the leading ":"
+func (s *Symbol) Name() string {
+	return s.name
+}
+
+func (s *Symbol) String() string {
+	return ":" + s.name
+}
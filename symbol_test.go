@@ -0,0 +1,99 @@
+package ergolas_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleIntern() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO(), ergolas.WithJSON())
+
+	_, err := evaluateIn(ctx, `
+		state := :open
+		println state " " (state == :open) " " (state == :closed)
+		println state.name " " ((symbol "open") == state)
+
+		config := json.parse "{\"open\": 1, \"closed\": 2}"
+		println (at config state) " " (at config :missing)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(ergolas.Intern("open") == ergolas.Intern("open"))
+
+	// Output:
+	// :open true false
+	// open true
+	// 1 <nil>
+	// true
+}
+
+func TestSymbolTypes(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`:a`, `Symbol`},
+		{`:(a + 1)`, `Quoted`},
+		{`symbol "with spaces"`, `Symbol`},
+	}
+
+	for _, test := range tests {
+		result, err := evaluateIn(ergolas.NewContext(ergolas.WithCore()), test.source)
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+
+		if typ := ergolas.TypeOf(result).String(); typ != test.expected {
+			t.Errorf("%s: expected type %s, got %s", test.source, test.expected, typ)
+		}
+	}
+
+	_, err := evaluateIn(ergolas.NewContext(ergolas.WithCore()), `x :: Symbol := :a; y :: Symbol := "a"`)
+	if err == nil || err.Error() != `expected value for "y" of type Symbol but got String` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSymbolAllocations(t *testing.T) {
+	ctx := ergolas.NewRootContext(ergolas.WithBindings(map[string]any{
+		"name": strings.Repeat("x", 4096),
+	}))
+
+	_, err := evaluateIn(ctx, `symbol name`, ergolas.EvalOptions{MaxAllocations: 1024})
+
+	var limitErr ergolas.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != ergolas.AllocationLimit {
+		t.Fatalf("expected allocations limit error, got %v", err)
+	}
+
+	// symbols that already exist are not charged again
+	ergolas.Intern("open")
+	if _, err := evaluateIn(ergolas.NewRootContext(), `symbol "open"`, ergolas.EvalOptions{MaxAllocations: 1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSymbolMapKeys(t *testing.T) {
+	m := ergolas.NewMap()
+	m.Set("k", int64(1))
+
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithBindings(map[string]any{"m": m}))
+
+	// map keys are strings so a symbol is the same key as its name
+	result, err := evaluateIn(ctx, `[(at m :k) (at m "k") ((at m :k) == (at m "k")) (at m :other)]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := fmt.Sprint(result); s != `[1 1 true <nil>]` {
+		t.Errorf("unexpected lookups %s", s)
+	}
+}
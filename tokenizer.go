@@ -45,12 +45,17 @@ type rule struct {
 	Type   TokenType
 	Regex  *regexp.Regexp
 	Ignore bool
+	// NotFollowedBy rejects a match followed by what it matches, Go regexps
+	// have no lookahead
+	NotFollowedBy *regexp.Regexp
 }
 
 var (
 	FloatToken       TokenType = "Float"
 	IntegerToken     TokenType = "Integer"
+	DurationToken    TokenType = "Duration"
 	StringToken      TokenType = "String"
+	RegexToken       TokenType = "Regex"
 	QuoteToken       TokenType = "Quote"
 	UnquoteToken     TokenType = "Unquote"
 	LOperatorToken   TokenType = "LOperator"
@@ -62,21 +67,30 @@ var (
 	NewlineToken     TokenType = "Newline"
 )
 
+//go:generate go run ./cmd/ergolas-grammars -out editors
+
 var rules = []rule{
+	{Type: DurationToken, // e.g. "500ms", "1.5h" or "1h30m" but not "10seconds"
+		Regex:         regexp.MustCompile(`^([0-9]+(\.[0-9]+)?(ns|us|ms|h|m|s))+`),
+		NotFollowedBy: regexp.MustCompile(`^[a-zA-Z0-9\-\_\$]`)},
 	{Type: FloatToken,
 		Regex: regexp.MustCompile(`^[0-9]+\.[0-9]+`)},
 	{Type: IntegerToken,
 		Regex: regexp.MustCompile(`^[0-9]+`)},
 	{Type: StringToken,
 		Regex: regexp.MustCompile(`^"(\\.|[^"])*"`)},
-	{Type: ROperatorToken, // The operators ":=", "::", "<-", "->" and "|>" are right associative
-		Regex: regexp.MustCompile(`^(\:\=|\:\:)`)},
+	{Type: RegexToken, // must come before identifiers, e.g. r"[a-z]+"
+		Regex: regexp.MustCompile(`^r"(\\.|[^"])*"`)},
+	{Type: ROperatorToken, // The operators ":=", "=", "::", "<-", "->" and "|>" are right associative
+		Regex: regexp.MustCompile(`^(\:\=|\:\:|\<\-|\-\>|\|\>)`)},
 	{Type: QuoteToken,
 		Regex: regexp.MustCompile(`^:`)},
 	{Type: UnquoteToken,
 		Regex: regexp.MustCompile(`^\$`)},
-	{Type: LOperatorToken,
-		Regex: regexp.MustCompile(`^[\+\-\*\/\%\=\<\>\!\&\|\^]+`)},
+	{Type: LOperatorToken, // a lone "=" is not a left operator, e.g. "==" and "<=" are
+		Regex: regexp.MustCompile(`^([\+\-\*\/\%\=\<\>\!\&\|\^]{2,}|[\+\-\*\/\%\<\>\!\&\|\^])`)},
+	{Type: ROperatorToken, // the assignment "="
+		Regex: regexp.MustCompile(`^\=`)},
 	{Type: PunctuationToken,
 		Regex: regexp.MustCompile(`^[\.\,\;\(\)\[\]\{\}]`)},
 	{Type: IdentifierToken,
@@ -89,18 +103,58 @@ var rules = []rule{
 		Regex: regexp.MustCompile(`^[ \t]+`)},
 }
 
+// TokenRule describes one of the rules used by the tokenizer, the rules are
+// tried in order and the first one matching at the current position wins.
+// A rule with NotFollowedBy doesn't match when the text after the match
+// matches that pattern.
+type TokenRule struct {
+	Type          TokenType
+	Pattern       string
+	Ignore        bool
+	NotFollowedBy string
+}
+
+// TokenRules returns the rules used by the tokenizer, this is used to generate
+// syntax highlighting grammars consistent with the real tokenizer.
+func TokenRules() []TokenRule {
+	result := make([]TokenRule, len(rules))
+	for i, r := range rules {
+		result[i] = TokenRule{Type: r.Type, Pattern: r.Regex.String(), Ignore: r.Ignore}
+		if r.NotFollowedBy != nil {
+			result[i].NotFollowedBy = r.NotFollowedBy.String()
+		}
+	}
+
+	return result
+}
+
 func matchRules(source string) (*Token, bool) {
 	for _, rule := range rules {
 		match := rule.Regex.FindString(source)
-		if match != "" {
-			return &Token{Type: rule.Type, Value: match}, rule.Ignore
+		if match == "" {
+			continue
+		}
+		if rule.NotFollowedBy != nil && rule.NotFollowedBy.MatchString(source[len(match):]) {
+			continue
 		}
+
+		return &Token{Type: rule.Type, Value: match}, rule.Ignore
 	}
 
 	return nil, true
 }
 
 func Tokenize(source string) ([]Token, error) {
+	return tokenize(source, false)
+}
+
+// TokenizeWithTrivia is like Tokenize but also keeps whitespace and comment
+// tokens, this is useful for tools that need to reproduce the source code.
+func TokenizeWithTrivia(source string) ([]Token, error) {
+	return tokenize(source, true)
+}
+
+func tokenize(source string, keepIgnored bool) ([]Token, error) {
 	cursor := 0
 	tokens := []Token{}
 
@@ -112,8 +166,9 @@ func Tokenize(source string) ([]Token, error) {
 			return nil, TokenizeError{&source, cursor, "unexpected character"}
 		}
 
+		t.Location = cursor
 		cursor += len(t.Value)
-		if !ignore {
+		if !ignore || keepIgnored {
 			tokens = append(tokens, *t)
 		}
 	}
@@ -0,0 +1,178 @@
+package ergolas
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Type is a type of the gradual type system used by type annotations with "::"
+type Type interface {
+	String() string
+}
+
+type BasicType string
+
+func (t BasicType) String() string {
+	return string(t)
+}
+
+var (
+	AnyType      BasicType = "Any"
+	NilType      BasicType = "Nil"
+	IntType      BasicType = "Int"
+	FloatType    BasicType = "Float"
+	StringType   BasicType = "String"
+	BoolType     BasicType = "Bool"
+	QuotedType   BasicType = "Quoted"
+	FnType       BasicType = "Fn"
+	MapType      BasicType = "Map"
+	ListType     BasicType = "List"
+	TaskType     BasicType = "Task"
+	ChanType     BasicType = "Chan"
+	TimeType     BasicType = "Time"
+	DurationType BasicType = "Duration"
+	RegexType    BasicType = "Regex"
+	SymbolType   BasicType = "Symbol"
+)
+
+// typeNames are the type names that can be used in annotations
+var typeNames = map[string]Type{
+	"Any":      AnyType,
+	"Nil":      NilType,
+	"Int":      IntType,
+	"Float":    FloatType,
+	"String":   StringType,
+	"Bool":     BoolType,
+	"Quoted":   QuotedType,
+	"Fn":       FnType,
+	"Map":      MapType,
+	"List":     ListType,
+	"Task":     TaskType,
+	"Chan":     ChanType,
+	"Time":     TimeType,
+	"Duration": DurationType,
+	"Regex":    RegexType,
+	"Symbol":   SymbolType,
+}
+
+// FunctionType is the static type of a function literal, unannotated
+// parameters and results are of type Any.
+type FunctionType struct {
+	Params []Type
+	Result Type
+}
+
+func (t FunctionType) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprint(sb, "Fn(")
+	for i, p := range t.Params {
+		if i > 0 {
+			fmt.Fprint(sb, ", ")
+		}
+		fmt.Fprint(sb, p)
+	}
+	fmt.Fprintf(sb, ") -> %v", t.Result)
+	return sb.String()
+}
+
+// TypeOf returns the runtime type of a value
+func TypeOf(v any) Type {
+	switch v := v.(type) {
+	case nil:
+		return NilType
+	case int64:
+		return IntType
+	case float64:
+		return FloatType
+	case string:
+		return StringType
+	case bool:
+		return BoolType
+	case *Symbol:
+		return SymbolType
+	case Node:
+		return QuotedType
+	case *Map:
+		return MapType
+	case *List:
+		return ListType
+	case *Task:
+		return TaskType
+	case *Channel:
+		return ChanType
+	case time.Time:
+		return TimeType
+	case time.Duration:
+		return DurationType
+	case *regexp.Regexp:
+		return RegexType
+	case *Function:
+		return v.Type()
+	case func(args ...any) (any, error), Builtin:
+		return FnType
+	}
+
+	return AnyType
+}
+
+// isAssignable tells whether a value of type "from" can be used where a value
+// of type "to" is expected, the type Any is compatible with every other type.
+func isAssignable(from, to Type) bool {
+	if from == AnyType || to == AnyType {
+		return true
+	}
+
+	fromFn, fromIsFn := from.(FunctionType)
+	toFn, toIsFn := to.(FunctionType)
+
+	if to == FnType {
+		return from == FnType || fromIsFn
+	}
+	if toIsFn {
+		if from == FnType {
+			return true
+		}
+		if !fromIsFn || len(fromFn.Params) != len(toFn.Params) {
+			return false
+		}
+		for i := range toFn.Params {
+			if !isAssignable(toFn.Params[i], fromFn.Params[i]) {
+				return false
+			}
+		}
+
+		return isAssignable(fromFn.Result, toFn.Result)
+	}
+
+	return from == to
+}
+
+// parseTypeExpression converts the right hand side of a type annotation to a type
+func parseTypeExpression(node Node) (Type, error) {
+	switch node.Type() {
+	case IdentifierNode:
+		name := node.Metadata()["Value"].(string)
+		typ, ok := typeNames[name]
+		if !ok {
+			return nil, fmt.Errorf(`unknown type "%s"`, name)
+		}
+
+		return typ, nil
+	case ParenthesisNode:
+		return parseTypeExpression(node.Children()[0])
+	}
+
+	return nil, fmt.Errorf(`invalid type expression`)
+}
+
+// checkValueType returns an error if the runtime type of a value is not
+// compatible with the expected type
+func checkValueType(v any, expected Type, what string) error {
+	if actual := TypeOf(v); !isAssignable(actual, expected) {
+		return fmt.Errorf(`expected %s of type %v but got %v`, what, expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package ergolas_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/aziis98/ergolas"
+)
+
+func ExampleParse_type_annotation() {
+	tokens, err := ergolas.Tokenize(`x :: Int := 1`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, err := ergolas.ParseExpression(tokens)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ergolas.PrintAST(node)
+
+	// Output:
+	// - Binary
+	//   - Binary
+	//     - Identifier { Value: "x" }
+	//     - Operator { Value: "::" }
+	//     - Identifier { Value: "Int" }
+	//   - Operator { Value: ":=" }
+	//   - Integer { Value: "1" }
+}
+
+func ExampleCheck() {
+	source := `
+		x :: Int := 1
+		x := "one"
+		add := fn (a :: Int) (b :: Int) :: Int { a + b }
+		add x 2.0
+		concat := fn (a :: String) :: Int { a + "!" }
+	`
+
+	tokens, err := ergolas.Tokenize(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, d := range ergolas.Check(node, ergolas.NewRootContext().Types()) {
+		fmt.Println(d.Format(source))
+	}
+
+	// Output:
+	// [3:8] error: cannot assign value of type String to "x" of type Int
+	// [5:9] error: cannot use value of type Float as Int in argument 2
+	// [6:39] error: cannot return value of type String from function returning Int
+}
+
+func ExampleEvaluate_type_annotations() {
+	ctx := ergolas.NewContext(ergolas.WithCore(), ergolas.WithIO())
+
+	_, err := evaluateIn(ctx, `
+		add := fn (a :: Int) (b :: Int) :: Int { a + b }
+		println (add 1 2)
+
+		x :: Int := 1
+		x = 2
+		println x
+
+		if true { x := "shadowed"; println x }
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = evaluateIn(ctx, `add 1 2.0`)
+	fmt.Println(err)
+
+	_, err = evaluateIn(ctx, `x = "one"`)
+	fmt.Println(err)
+
+	// Output:
+	// 3
+	// 2
+	// shadowed
+	// expected argument "b" of type Int but got Float
+	// expected value for "x" of type Int but got String
+}
+
+func TestRuntimeTypeChecks(t *testing.T) {
+	result, err := evaluateIn(ergolas.NewRootContext(), `
+		a := 1
+		a :: Int
+
+		b :: Int := 1
+		b :: String := "redeclared"
+
+		c :: Any := 1
+		c = "any"
+
+		inc := fn (n :: Int) { n = n + 1; n }
+
+		[a b c (inc 1)]
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := fmt.Sprint(result); s != `[1 "redeclared" "any" 2]` {
+		t.Errorf("unexpected results %s", s)
+	}
+
+	failures := map[string]string{
+		`f := fn :: String { 42 }; f 1`:                     `expected 0 arguments, got 1`,
+		`x :: Float := 1`:                                   `expected value for "x" of type Float but got Int`,
+		`x :: Foo := 1`:                                     `unknown type "Foo"`,
+		`x :: Int := 1; x := "one"`:                         `expected value for "x" of type Int but got String`,
+		`x :: Int := 1; f := fn { x = "one" }; call f`:      `expected value for "x" of type Int but got String`,
+		`[x y] := [1 2]; x :: Int := 3; [x y] := ["a" "b"]`: `expected value for "x" of type Int but got String`,
+		`f := fn (a :: Int) { a = "one" }; f 1`:             `expected value for "a" of type Int but got String`,
+	}
+
+	for source, expected := range failures {
+		_, err := evaluateIn(ergolas.NewRootContext(), source)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error %q, got %v", source, expected, err)
+		}
+	}
+}
+
+// checkSource type checks the source and returns the formatted diagnostics
+func checkSource(t *testing.T, source string) []string {
+	t.Helper()
+
+	tokens, err := ergolas.Tokenize(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := ergolas.Parse(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []string{}
+	for _, d := range ergolas.Check(node, ergolas.NewRootContext().Types()) {
+		messages = append(messages, d.Format(source))
+	}
+
+	return messages
+}
+
+func TestCheckPipeline(t *testing.T) {
+	source := `
+		add := fn (a :: Int) (b :: Int) :: Int { a + b }
+		"one" |> add 1
+		1 |> add
+		inc := (x :: Int) -> x + 1
+		inc "two"
+	`
+
+	messages := checkSource(t, source)
+
+	expected := []string{
+		`[3:3] error: cannot use value of type String as Int in argument 1`,
+		`[4:3] error: expected 2 arguments, got 1`,
+		`[6:7] error: cannot use value of type String as Int in argument 1`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+func TestSetTyped(t *testing.T) {
+	ctx := ergolas.NewRootContext()
+	if err := ctx.SetTyped("limit", int64(10), ergolas.IntType); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctx.Assign("limit", "ten"); err == nil || err.Error() != `expected value for "limit" of type Int but got String` {
+		t.Errorf("expected type error, got %v", err)
+	}
+	if err := ctx.Set("limit", 2.5); err == nil {
+		t.Errorf("expected type error redeclaring limit")
+	}
+	if err := ctx.SetTyped("limit", "ten", ergolas.IntType); err == nil {
+		t.Errorf("expected type error declaring limit")
+	}
+	if v, _ := ctx.GetKey("limit"); v != int64(10) {
+		t.Errorf("expected limit to be unchanged, got %v", v)
+	}
+}